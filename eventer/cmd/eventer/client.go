@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/client"
+	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+	"github.com/sibedge-llc/dp-services/eventer/internal/event"
+)
+
+func actionAdd() {
+	schema, err := ioutil.ReadFile(*addSchema)
+	if err != nil {
+		app.Fatalf("failed to read schema: %v", err)
+	}
+
+	eventId := *addEventId
+	if eventId == "" {
+		eventId = strings.TrimSuffix(filepath.Base(*addSchema), filepath.Ext(*addSchema))
+	}
+	eventDesc := event.EventDesc{
+		Id:       eventId,
+		Dataset:  *addDataset,
+		Schema:   schema,
+		Count:    *addCount,
+		Interval: *addInterval,
+		Seed:     addSeed.value,
+	}
+	if *addRate > 0 {
+		eventDesc.Rate = &event.RateDesc{Type: event.RateTypeConstant, Rate: *addRate}
+	}
+
+	destinationDesc, err := newDestinationDesc(*addDestinationType, *addTarget, *addDestination)
+	if err != nil {
+		app.Fatalf("%v", err)
+	}
+
+	response, err := newClient().Add(event.GeneratorDesc{
+		Events:       []event.EventDesc{eventDesc},
+		Destinations: []event.DestinationDesc{destinationDesc},
+		Schedules: []event.ScheduleDesc{{
+			DestinationId: destinationDesc.Id,
+			EventId:       eventDesc.Id,
+		}},
+	})
+	if err != nil {
+		app.Fatalf("%v", err)
+	}
+	printObject(response)
+}
+
+func actionList() {
+	response, err := newClient().List(client.ListFilter{
+		Type:    *listType,
+		Dataset: *listDataset,
+		State:   *listState,
+		Cursor:  *listCursor,
+		Limit:   *listLimit,
+	})
+	if err != nil {
+		app.Fatalf("%v", err)
+	}
+	printObject(response)
+}
+
+func actionStatus(id string) {
+	response, err := newClient().Status(id)
+	if err != nil {
+		app.Fatalf("%v", err)
+	}
+	printObject(response)
+}
+
+func actionRemove(id string) {
+	err := newClient().Remove(id)
+	if err != nil {
+		app.Fatalf("%v", err)
+	}
+}
+
+func newClient() *client.Client {
+	return client.New(*serviceUrl, *serviceTimeout)
+}
+
+// newDestinationDesc makes destination of the type, config file may supply connection settings
+func newDestinationDesc(destinationType string, target string, configFile string) (event.DestinationDesc, error) {
+	desc := event.DestinationDesc{
+		Id:   "d1",
+		Type: destinationType,
+	}
+	if configFile != "" {
+		data, err := ioutil.ReadFile(configFile)
+		if err != nil {
+			return desc, fmt.Errorf("failed to read destination config: %w", err)
+		}
+		err = json.Unmarshal(data, &desc)
+		if err != nil {
+			return desc, fmt.Errorf("failed to parse destination config %s: %w", configFile, err)
+		}
+		desc.Type = destinationType
+	}
+
+	switch destinationType {
+	case event.DestinationTypeKafka:
+		if desc.Kafka == nil {
+			desc.Kafka = &config.KafkaConfig{}
+		}
+		desc.Kafka.Topic = target
+	case event.DestinationTypePostgres:
+		if desc.Postgres == nil {
+			desc.Postgres = &config.PostgresConfig{}
+		}
+		desc.Postgres.Table = target
+	case event.DestinationTypeHttp:
+		if desc.Http == nil {
+			desc.Http = &config.HttpConfig{}
+		}
+		desc.Http.Url = target
+	}
+	return desc, nil
+}
+
+func printObject(o interface{}) {
+	e := json.NewEncoder(os.Stdout)
+	e.SetIndent("", "  ")
+	err := e.Encode(o)
+	if err != nil {
+		app.Fatalf("failed to print response: %v", err)
+	}
+}
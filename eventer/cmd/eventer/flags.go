@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// optionalInt64 is the flag value that stays nil unless the flag is supplied
+type optionalInt64 struct {
+	value *int64
+}
+
+func (v *optionalInt64) Set(s string) error {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	v.value = &n
+	return nil
+}
+
+func (v *optionalInt64) String() string {
+	if v.value == nil {
+		return ""
+	}
+	return fmt.Sprint(*v.value)
+}
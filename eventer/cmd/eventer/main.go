@@ -3,17 +3,21 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/alecthomas/kingpin"
 	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+	"github.com/sibedge-llc/dp-services/eventer/internal/event"
 	"github.com/sibedge-llc/dp-services/eventer/internal/generator"
 	"github.com/sibedge-llc/dp-services/eventer/internal/kafka"
 	"github.com/sibedge-llc/dp-services/eventer/internal/postgres"
 	"github.com/sibedge-llc/dp-services/eventer/internal/service"
+	"github.com/sibedge-llc/dp-services/eventer/internal/webhook"
 	"go.uber.org/zap"
 )
 
@@ -22,12 +26,63 @@ var (
 	commandStart = app.Command("start", "Start generate events.")
 
 	startConfig = commandStart.Flag("config", "Config file.").Default("config.yaml").String()
+
+	commandRender = app.Command("render", "Render events to stdout as NDJSON.")
+	renderSchema  = commandRender.Flag("schema", "Jsonnet schema file.").Required().ExistingFile()
+	renderCount   = commandRender.Flag("count", "Number of events.").Default("1").Int()
+	renderDataset = commandRender.Flag("dataset", "Dataset name.").String()
+	renderGroup   = commandRender.Flag("group", "Instance id returned by get_instance_id.").String()
+
+	serviceUrl     = app.Flag("url", "Eventer service url.").Default("http://localhost:9099").Envar("EVENTER_URL").String()
+	serviceTimeout = app.Flag("timeout", "Eventer service request timeout.").Default("30s").Duration()
+
+	commandAdd         = app.Command("add", "Add generator to the running service.")
+	addSchema          = commandAdd.Flag("schema", "Jsonnet schema file.").Required().ExistingFile()
+	addEventId         = commandAdd.Flag("event-id", "Event id, schema file name by default.").String()
+	addDataset         = commandAdd.Flag("dataset", "Dataset name.").String()
+	addCount           = commandAdd.Flag("count", "Number of events, infinite if not positive.").Int64()
+	addInterval        = commandAdd.Flag("interval", "Interval between events.").Default("1s").String()
+	addRate            = commandAdd.Flag("rate", "Constant rate of events per second, overrides interval.").Float64()
+	addDestinationType = commandAdd.Flag("type", "Destination type.").Default(event.DestinationTypeKafka).Enum(event.DestinationTypeKafka, event.DestinationTypePostgres, event.DestinationTypeHttp)
+	addTarget          = commandAdd.Flag("target", "Kafka topic, postgres table or http url.").Required().String()
+	addDestination     = commandAdd.Flag("destination", "JSON file with destination config.").ExistingFile()
+
+	commandList = app.Command("list", "List generators of the running service.")
+	listType    = commandList.Flag("type", "Destination type filter.").String()
+	listDataset = commandList.Flag("dataset", "Dataset filter.").String()
+	listState   = commandList.Flag("state", "State filter.").String()
+	listCursor  = commandList.Flag("cursor", "Cursor returned by the previous page.").String()
+	listLimit   = commandList.Flag("limit", "Page size.").Int()
+
+	commandStatus = app.Command("status", "Show generator status.")
+	statusId      = commandStatus.Arg("id", "Generator id.").Required().String()
+
+	commandRemove = app.Command("remove", "Stop and remove generator.")
+	removeId      = commandRemove.Arg("id", "Generator id.").Required().String()
+
+	renderSeed optionalInt64
+	addSeed    optionalInt64
 )
 
+func init() {
+	commandRender.Flag("seed", "Seed of random values, the same seed renders the same events.").SetValue(&renderSeed)
+	commandAdd.Flag("seed", "Seed of random values, the same seed produces the same events.").SetValue(&addSeed)
+}
+
 func main() {
 	switch kingpin.MustParse(app.Parse(os.Args[1:])) {
 	case commandStart.FullCommand():
 		actionStart(*startConfig)
+	case commandRender.FullCommand():
+		actionRender(*renderSchema, *renderCount, *renderDataset, *renderGroup, renderSeed.value)
+	case commandAdd.FullCommand():
+		actionAdd()
+	case commandList.FullCommand():
+		actionList()
+	case commandStatus.FullCommand():
+		actionStatus(*statusId)
+	case commandRemove.FullCommand():
+		actionRemove(*removeId)
 	}
 }
 
@@ -65,13 +120,34 @@ func actionStart(configFile string) {
 		return
 	}
 
-	generatorService, err := generator.New(ctx, cfg.InstanceId)
+	webhookService := webhook.New(ctx, &cfg.Http)
+
+	registry, err := newRegistry(ctx, cfg)
+	if err != nil {
+		zap.L().Panic("create generator registry failed", zap.Error(err))
+		return
+	}
+
+	syncInterval, err := time.ParseDuration(cfg.Registry.SyncInterval)
+	if err != nil {
+		zap.L().Panic("parse registry sync interval failed", zap.Error(err))
+		return
+	}
+
+	generatorService, err := generator.New(ctx, cfg.InstanceId, registry, syncInterval, cfg.Registry.Secrets)
 	if err != nil {
 		zap.L().Panic("create generator service failed", zap.Error(err))
 		return
 	}
+	defer generatorService.Close()
 
-	service := service.New(&cfg.Service, kafkaService, postgresService, generatorService)
+	service := service.New(&cfg.Service, kafkaService, postgresService, webhookService, generatorService)
+
+	err = service.RestoreGenerators()
+	if err != nil {
+		zap.L().Panic("restore generators failed", zap.Error(err))
+		return
+	}
 
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
@@ -86,3 +162,16 @@ func actionStart(configFile string) {
 
 	<-ch
 }
+
+func newRegistry(ctx context.Context, cfg *config.Config) (generator.Registry, error) {
+	switch cfg.Registry.Type {
+	case config.RegistryTypeNone:
+		return nil, nil
+	case config.RegistryTypeFile:
+		return generator.NewFileRegistry(cfg.Registry.Path)
+	case config.RegistryTypePostgres:
+		return postgres.NewRegistry(ctx, &cfg.Postgres, cfg.InstanceId, cfg.Registry.Table, time.Second)
+	default:
+		return nil, fmt.Errorf("unknown registry type: %s", cfg.Registry.Type)
+	}
+}
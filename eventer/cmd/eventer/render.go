@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/event"
+)
+
+func actionRender(schemaFile string, count int, dataset string, group string, seed *int64) {
+	composer, err := event.NewComposerByFile(dataset, group, schemaFile)
+	if err != nil {
+		app.Fatalf("failed to create composer: %v", err)
+	}
+	if seed != nil {
+		composer.SetSeed(*seed)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	var line bytes.Buffer
+	for i := 0; i < count; i++ {
+		evt, err := composer.Compose()
+		if err != nil {
+			w.Flush()
+			app.Fatalf("failed to render event: %v", err)
+		}
+		// Jsonnet output is indented, every event must fit a single line
+		line.Reset()
+		err = json.Compact(&line, evt.Json)
+		if err != nil {
+			w.Flush()
+			app.Fatalf("failed to compact event: %v", err)
+		}
+		line.WriteByte('\n')
+		w.Write(line.Bytes())
+	}
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/event"
+	"github.com/sibedge-llc/dp-services/eventer/internal/service"
+)
+
+type Client struct {
+	url  string
+	http *http.Client
+}
+
+type ListFilter struct {
+	Type    string
+	Dataset string
+	State   string
+	Cursor  string
+	Limit   int
+}
+
+func New(baseUrl string, timeout time.Duration) *Client {
+	return &Client{
+		url:  strings.TrimRight(baseUrl, "/"),
+		http: &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *Client) Add(desc event.GeneratorDesc) (*service.AddGeneratorResponse, error) {
+	var response service.AddGeneratorResponse
+	err := c.post("/generator/add", desc, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (c *Client) List(filter ListFilter) (*service.ListGeneratorsResponse, error) {
+	query := url.Values{}
+	setQuery(query, "type", filter.Type)
+	setQuery(query, "dataset", filter.Dataset)
+	setQuery(query, "state", filter.State)
+	setQuery(query, "cursor", filter.Cursor)
+	if filter.Limit > 0 {
+		query.Set("limit", fmt.Sprint(filter.Limit))
+	}
+
+	var response service.ListGeneratorsResponse
+	err := c.get("/generators", query, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (c *Client) Status(id string) (*service.GeneratorStatus, error) {
+	var response service.GeneratorStatus
+	err := c.get("/generator/status", url.Values{"id": {id}}, &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (c *Client) Remove(id string) error {
+	request := struct {
+		Id string `json:"id"`
+	}{
+		Id: id,
+	}
+	return c.post("/generator/remove", request, nil)
+}
+
+func (c *Client) get(path string, query url.Values, out interface{}) error {
+	u := c.url + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	return c.do(req, out)
+}
+
+func (c *Client) post(path string, in interface{}, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.url+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.do(req, out)
+}
+
+func (c *Client) do(req *http.Request, out interface{}) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("request %s failed: %w", req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		errResponse := struct {
+			Error string `json:"error"`
+		}{}
+		if json.Unmarshal(data, &errResponse) != nil || errResponse.Error == "" {
+			errResponse.Error = http.StatusText(resp.StatusCode)
+		}
+		return fmt.Errorf("request %s failed with status %d: %s", req.URL.Path, resp.StatusCode, errResponse.Error)
+	}
+
+	if out == nil {
+		return nil
+	}
+	err = json.Unmarshal(data, out)
+	if err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+func setQuery(query url.Values, key string, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
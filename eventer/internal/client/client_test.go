@@ -0,0 +1,43 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/service"
+)
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/generators":
+			if r.URL.Query().Get("state") != "paused" {
+				service.WriteError(w, http.StatusBadRequest, "unexpected state")
+				return
+			}
+			service.WriteObject(w, service.ListGeneratorsResponse{
+				Generators: []service.GeneratorStatus{{Id: "1"}},
+				Cursor:     "1",
+			})
+		default:
+			service.WriteError(w, http.StatusNotFound, "generator with id is not found: 2")
+		}
+	}))
+	defer server.Close()
+
+	c := New(server.URL+"/", time.Second)
+	list, err := c.List(ListFilter{State: "paused"})
+	if err != nil {
+		t.Fatal("list generators failed", err)
+	}
+	if len(list.Generators) != 1 || list.Generators[0].Id != "1" || list.Cursor != "1" {
+		t.Errorf("unexpected list response %+v", list)
+	}
+
+	_, err = c.Status("2")
+	if err == nil || err.Error() != "request /generator/status failed with status 404: generator with id is not found: 2" {
+		t.Errorf("unexpected status error %v", err)
+	}
+}
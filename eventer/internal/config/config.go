@@ -9,30 +9,176 @@ type Config struct {
 	InstanceId string         `yaml:"instance_id"`
 	Kafka      KafkaConfig    `yaml:"kafka"`
 	Postgres   PostgresConfig `yaml:"postgres"`
+	Http       HttpConfig     `yaml:"http"`
 	Service    ServiceConfig  `yaml:"service"`
+	Registry   RegistryConfig `yaml:"registry"`
 }
 
 type ServiceConfig struct {
 	Listen string `yaml:"listen"`
 }
 
+const (
+	RegistryTypeNone     = "none"
+	RegistryTypeFile     = "file"
+	RegistryTypePostgres = "postgres"
+)
+
+type RegistryConfig struct {
+	Type         string `yaml:"type"`
+	Path         string `yaml:"path"`
+	Table        string `yaml:"table"`
+	SyncInterval string `yaml:"sync_interval"`
+	Secrets      bool   `yaml:"secrets"`
+}
+
 type LoggingConfig struct {
 	Level string `yaml:"level"`
 }
 
+type KafkaSaslConfig struct {
+	Mechanism string `yaml:"mechanism" json:"mechanism,omitempty"`
+	Username  string `yaml:"username" json:"username,omitempty"`
+	Password  string `yaml:"password" json:"password,omitempty"`
+}
+
+type KafkaSslConfig struct {
+	CaLocation          string `yaml:"ca_location" json:"ca_location,omitempty"`
+	CertificateLocation string `yaml:"certificate_location" json:"certificate_location,omitempty"`
+	KeyLocation         string `yaml:"key_location" json:"key_location,omitempty"`
+	KeyPassword         string `yaml:"key_password" json:"key_password,omitempty"`
+}
+
+type KafkaTopicConfig struct {
+	Partitions        int               `yaml:"partitions" json:"partitions,omitempty"`
+	ReplicationFactor int               `yaml:"replication_factor" json:"replication_factor,omitempty"`
+	Configs           map[string]string `yaml:"configs" json:"configs,omitempty"`
+	MustExist         bool              `yaml:"must_exist" json:"must_exist,omitempty"`
+}
+
+type KafkaKeyConfig struct {
+	Fields    []string `yaml:"fields" json:"fields"`
+	Separator string   `yaml:"separator" json:"separator,omitempty"`
+}
+
+const (
+	KafkaFormatJson     = "json"
+	KafkaFormatAvro     = "avro"
+	KafkaFormatProtobuf = "protobuf"
+)
+
+type KafkaSchemaRegistryConfig struct {
+	Url      string `yaml:"url" json:"url,omitempty"`
+	Username string `yaml:"username" json:"username,omitempty"`
+	Password string `yaml:"password" json:"password,omitempty"`
+}
+
+type KafkaTransactionConfig struct {
+	Messages   int     `yaml:"messages" json:"messages,omitempty"`
+	Duration   string  `yaml:"duration" json:"duration,omitempty"`
+	AbortRatio float64 `yaml:"abort_ratio" json:"abort_ratio,omitempty"`
+	IdPrefix   string  `yaml:"id_prefix" json:"id_prefix,omitempty"`
+}
+
 type KafkaConfig struct {
-	BootstrapServers string `yaml:"bootstrap_servers"`
-	Topic            string `yaml:"topic"`
+	BootstrapServers string                     `yaml:"bootstrap_servers"`
+	Topic            string                     `yaml:"topic"`
+	ClientId         string                     `yaml:"client_id" json:"client_id,omitempty"`
+	SecurityProtocol string                     `yaml:"security_protocol" json:"security_protocol,omitempty"`
+	Sasl             *KafkaSaslConfig           `yaml:"sasl" json:"sasl,omitempty"`
+	Ssl              *KafkaSslConfig            `yaml:"ssl" json:"ssl,omitempty"`
+	Properties       map[string]interface{}     `yaml:"properties" json:"properties,omitempty"`
+	TopicConfig      *KafkaTopicConfig          `yaml:"topic_config" json:"topic_config,omitempty"`
+	Key              *KafkaKeyConfig            `yaml:"key" json:"key,omitempty"`
+	Partitioner      string                     `yaml:"partitioner" json:"partitioner,omitempty"`
+	Envelope         bool                       `yaml:"envelope" json:"envelope,omitempty"`
+	Format           string                     `yaml:"format" json:"format,omitempty"`
+	Schema           string                     `yaml:"schema" json:"schema,omitempty"`
+	Subject          string                     `yaml:"subject" json:"subject,omitempty"`
+	ProtoMessage     string                     `yaml:"proto_message" json:"proto_message,omitempty"`
+	SchemaRegistry   *KafkaSchemaRegistryConfig `yaml:"schema_registry" json:"schema_registry,omitempty"`
+	Transaction      *KafkaTransactionConfig    `yaml:"transaction" json:"transaction,omitempty"`
+}
+
+const (
+	PostgresModeUpsert = "upsert"
+	PostgresModeAppend = "append"
+	PostgresModeIgnore = "ignore"
+	PostgresModeScd2   = "scd2"
+)
+
+const (
+	PostgresEvolutionEvolve = "evolve"
+	PostgresEvolutionIgnore = "ignore"
+	PostgresEvolutionReject = "reject"
+)
+
+type PostgresColumnConfig struct {
+	Type     string `yaml:"type" json:"type"`
+	Nullable *bool  `yaml:"nullable" json:"nullable,omitempty"`
+	Key      bool   `yaml:"key" json:"key,omitempty"`
+	Default  string `yaml:"default" json:"default,omitempty"`
+}
+
+type PostgresFlattenConfig struct {
+	Separator string `yaml:"separator" json:"separator,omitempty"`
+	Depth     int    `yaml:"depth" json:"depth,omitempty"`
+	Explode   bool   `yaml:"explode" json:"explode,omitempty"`
+}
+
+const (
+	PostgresPartitionDaily   = "daily"
+	PostgresPartitionMonthly = "monthly"
+)
+
+type PostgresPartitionConfig struct {
+	Field    string `yaml:"field" json:"field"`
+	Interval string `yaml:"interval" json:"interval"`
 }
 
 type PostgresConfig struct {
-	Host     string `yaml:"host"`
-	Port     int    `yaml:"port"`
-	User     string `yaml:"user"`
-	Password string `yaml:"password"`
-	Db       string `yaml:"db"`
-	Table    string `yaml:"table"`
-	Ssl      bool   `yaml:"ssl"`
+	Host             string                          `yaml:"host"`
+	Port             int                             `yaml:"port"`
+	User             string                          `yaml:"user"`
+	Password         string                          `yaml:"password"`
+	Db               string                          `yaml:"db"`
+	Table            string                          `yaml:"table"`
+	Ssl              bool                            `yaml:"ssl"`
+	BatchSize        int                             `yaml:"batch_size" json:"batch_size,omitempty"`
+	FlushInterval    string                          `yaml:"flush_interval" json:"flush_interval,omitempty"`
+	Mode             string                          `yaml:"mode" json:"mode,omitempty"`
+	Keys             []string                        `yaml:"keys" json:"keys,omitempty"`
+	Columns          map[string]PostgresColumnConfig `yaml:"columns" json:"columns,omitempty"`
+	Flatten          *PostgresFlattenConfig          `yaml:"flatten" json:"flatten,omitempty"`
+	Evolution        string                          `yaml:"evolution" json:"evolution,omitempty"`
+	Partition        *PostgresPartitionConfig        `yaml:"partition" json:"partition,omitempty"`
+	MaxOpenConns     int                             `yaml:"max_open_conns" json:"max_open_conns,omitempty"`
+	MaxIdleConns     int                             `yaml:"max_idle_conns" json:"max_idle_conns,omitempty"`
+	ConnMaxLifetime  string                          `yaml:"conn_max_lifetime" json:"conn_max_lifetime,omitempty"`
+	StatementTimeout string                          `yaml:"statement_timeout" json:"statement_timeout,omitempty"`
+	RetryAttempts    int                             `yaml:"retry_attempts" json:"retry_attempts,omitempty"`
+	RetryBackoff     string                          `yaml:"retry_backoff" json:"retry_backoff,omitempty"`
+	RetryMaxBackoff  string                          `yaml:"retry_max_backoff" json:"retry_max_backoff,omitempty"`
+}
+
+const (
+	HttpBatchFormatArray  = "array"
+	HttpBatchFormatNdjson = "ndjson"
+)
+
+type HttpConfig struct {
+	Url             string            `yaml:"url" json:"url,omitempty"`
+	Method          string            `yaml:"method" json:"method,omitempty"`
+	Headers         map[string]string `yaml:"headers" json:"headers,omitempty"`
+	AuthToken       string            `yaml:"auth_token" json:"auth_token,omitempty"`
+	Timeout         string            `yaml:"timeout" json:"timeout,omitempty"`
+	BatchSize       int               `yaml:"batch_size" json:"batch_size,omitempty"`
+	BatchFormat     string            `yaml:"batch_format" json:"batch_format,omitempty"`
+	FlushInterval   string            `yaml:"flush_interval" json:"flush_interval,omitempty"`
+	Concurrency     int               `yaml:"concurrency" json:"concurrency,omitempty"`
+	RetryAttempts   int               `yaml:"retry_attempts" json:"retry_attempts,omitempty"`
+	RetryBackoff    string            `yaml:"retry_backoff" json:"retry_backoff,omitempty"`
+	RetryMaxBackoff string            `yaml:"retry_max_backoff" json:"retry_max_backoff,omitempty"`
 }
 
 func LoadConfig(configFile string) (*Config, error) {
@@ -47,5 +193,66 @@ func LoadConfig(configFile string) (*Config, error) {
 		return nil, err
 	}
 
+	if cfg.Registry.Type == "" {
+		cfg.Registry.Type = RegistryTypeFile
+	}
+	if cfg.Registry.Path == "" {
+		cfg.Registry.Path = "registry.json"
+	}
+	if cfg.Registry.Table == "" {
+		cfg.Registry.Table = "eventer_generators"
+	}
+	if cfg.Postgres.BatchSize == 0 {
+		cfg.Postgres.BatchSize = 100
+	}
+	if cfg.Postgres.FlushInterval == "" {
+		cfg.Postgres.FlushInterval = "1s"
+	}
+	if cfg.Postgres.Mode == "" {
+		cfg.Postgres.Mode = PostgresModeUpsert
+	}
+	if cfg.Postgres.Evolution == "" {
+		cfg.Postgres.Evolution = PostgresEvolutionEvolve
+	}
+	if cfg.Postgres.RetryAttempts == 0 {
+		cfg.Postgres.RetryAttempts = 3
+	}
+	if cfg.Postgres.RetryBackoff == "" {
+		cfg.Postgres.RetryBackoff = "100ms"
+	}
+	if cfg.Postgres.RetryMaxBackoff == "" {
+		cfg.Postgres.RetryMaxBackoff = "5s"
+	}
+	if cfg.Http.Method == "" {
+		cfg.Http.Method = "POST"
+	}
+	if cfg.Http.Timeout == "" {
+		cfg.Http.Timeout = "10s"
+	}
+	if cfg.Http.BatchSize == 0 {
+		cfg.Http.BatchSize = 1
+	}
+	if cfg.Http.BatchFormat == "" {
+		cfg.Http.BatchFormat = HttpBatchFormatArray
+	}
+	if cfg.Http.FlushInterval == "" {
+		cfg.Http.FlushInterval = "1s"
+	}
+	if cfg.Http.Concurrency == 0 {
+		cfg.Http.Concurrency = 1
+	}
+	if cfg.Http.RetryAttempts == 0 {
+		cfg.Http.RetryAttempts = 3
+	}
+	if cfg.Http.RetryBackoff == "" {
+		cfg.Http.RetryBackoff = "100ms"
+	}
+	if cfg.Http.RetryMaxBackoff == "" {
+		cfg.Http.RetryMaxBackoff = "5s"
+	}
+	if cfg.Registry.SyncInterval == "" {
+		cfg.Registry.SyncInterval = "10s"
+	}
+
 	return &cfg, nil
 }
@@ -9,10 +9,15 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/go-jsonnet"
 	"github.com/google/go-jsonnet/ast"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/metrics"
 )
 
 type EventJson []byte
@@ -20,52 +25,97 @@ type EventJson []byte
 var NoEventJson EventJson
 
 type Composer struct {
+	lock     sync.Mutex
+	rand     *rand.Rand
 	vm       *jsonnet.VM
+	code     ast.Node
 	contents jsonnet.Contents
 	name     string
-}
-
-func init() {
-	rand.Seed(time.Now().Unix())
+	dataset  atomic.Value
+	composed prometheus.Counter
+	duration prometheus.Observer
 }
 
 func NewComposerByFile(dataset string, group string, filePath string) (*Composer, error) {
-	vm := jsonnet.MakeVM()
-	for _, f := range getFuncs(dataset, group) {
-		vm.NativeFunction(f)
-	}
-
 	fileData, err := ioutil.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
 
 	file := filepath.Base(filePath)
-	contents := jsonnet.MakeContents(string(fileData))
-
-	return &Composer{vm: vm, name: file, contents: contents}, nil
-
+	return NewComposerByContent(dataset, group, file, fileData)
 }
 
 func NewComposerByContent(dataset string, instanceId string, name string, data []byte) (*Composer, error) {
-	vm := jsonnet.MakeVM()
-	for _, f := range getFuncs(dataset, instanceId) {
-		vm.NativeFunction(f)
+	c := &Composer{
+		rand:     newRand(nil),
+		vm:       jsonnet.MakeVM(),
+		name:     name,
+		contents: jsonnet.MakeContents(string(data)),
+	}
+	c.SetDataset(dataset)
+	for _, f := range getFuncs(c.GetDataset, instanceId, c.rand) {
+		c.vm.NativeFunction(f)
 	}
 
-	contents := jsonnet.MakeContents(string(data))
+	// Schema is parsed once, evaluation of the parsed schema calls native functions every time
+	code, err := jsonnet.SnippetToAST(c.name, c.contents.String())
+	if err != nil {
+		return nil, err
+	}
+	c.code = code
 
-	return &Composer{vm: vm, name: name, contents: contents}, nil
+	return c, nil
+}
 
+func (c *Composer) SetDataset(dataset string) {
+	c.dataset.Store(dataset)
+}
+
+func (c *Composer) GetDataset() string {
+	return c.dataset.Load().(string)
+}
+
+// EnableMetrics makes the composer of the generator report its metrics, composers of previews don't report any
+func (c *Composer) EnableMetrics(generatorId string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.composed = metrics.EventsComposed.WithLabelValues(generatorId)
+	c.duration = metrics.EvaluationDuration.WithLabelValues(generatorId)
+}
+
+// SetSeed makes native functions produce the same sequence of values for the same seed
+func (c *Composer) SetSeed(seed int64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.rand.Seed(seed)
+}
+
+func (c *Composer) Compose() (*Event, error) {
+	eventJson, obj, err := c.NewEvent()
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	composed := c.composed
+	c.lock.Unlock()
+	if composed != nil {
+		composed.Inc()
+	}
+	return &Event{Json: eventJson, Id: GetId(obj), Object: obj}, nil
 }
 
 func (c *Composer) NewEvent() (EventJson, EventObject, error) {
-	c.vm.Importer(&jsonnet.MemoryImporter{Data: map[string]jsonnet.Contents{c.name: c.contents}})
-	code, err := jsonnet.SnippetToAST("<snippet>", fmt.Sprintf(`import "%s"`, c.name))
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	started := time.Now()
+	v, err := c.vm.Evaluate(c.code)
+	if c.duration != nil {
+		c.duration.Observe(time.Since(started).Seconds())
+	}
 	if err != nil {
 		return nil, nil, err
 	}
-	v, err := c.vm.Evaluate(code)
 	eventJson := EventJson(v)
 	var eventObject EventObject
 	err = json.Unmarshal(eventJson, &eventObject)
@@ -75,13 +125,14 @@ func (c *Composer) NewEvent() (EventJson, EventObject, error) {
 	return eventJson, eventObject, err
 }
 
-func getFuncs(dataset string, instanceId string) []*jsonnet.NativeFunction {
+// Native functions are called by the vm under the composer lock, so the random source is not shared
+func getFuncs(getDataset func() string, instanceId string, r *rand.Rand) []*jsonnet.NativeFunction {
 	return []*jsonnet.NativeFunction{
 		{
 			Params: ast.Identifiers{"default"},
 			Name:   "get_dataset",
 			Func: func(args []interface{}) (interface{}, error) {
-				if dataset != "" {
+				if dataset := getDataset(); dataset != "" {
 					return dataset, nil
 				}
 				v, err := getOneStringArg(args)
@@ -114,7 +165,7 @@ func getFuncs(dataset string, instanceId string) []*jsonnet.NativeFunction {
 					return []interface{}{}, err
 				}
 				vals := strings.Split(v, ",")
-				val := strings.Trim(vals[rand.Intn(len(vals))], " ")
+				val := strings.Trim(vals[r.Intn(len(vals))], " ")
 				return val, nil
 			},
 		},
@@ -148,7 +199,7 @@ func getFuncs(dataset string, instanceId string) []*jsonnet.NativeFunction {
 				if diff < dur {
 					dur = diff
 				}
-				ts := rand.Int63n(diff / dur)
+				ts := r.Int63n(diff / dur)
 				res := time.Duration(fromTime.Add(time.Duration(ts * dur)).UnixNano()).Seconds()
 				return res, nil
 			},
@@ -171,7 +222,7 @@ func getFuncs(dataset string, instanceId string) []*jsonnet.NativeFunction {
 				if to == from {
 					return to, nil
 				}
-				diff := rand.Int63n(to - from)
+				diff := r.Int63n(to - from)
 				return float64(from + diff), nil
 			},
 		},
@@ -194,7 +245,7 @@ func getFuncs(dataset string, instanceId string) []*jsonnet.NativeFunction {
 					return to, nil
 				}
 
-				v := (from + to) * rand.Float64()
+				v := (from + to) * r.Float64()
 
 				return v, nil
 			},
@@ -203,14 +254,14 @@ func getFuncs(dataset string, instanceId string) []*jsonnet.NativeFunction {
 			Params: ast.Identifiers{},
 			Name:   "get_rand_data",
 			Func: func(args []interface{}) (interface{}, error) {
-				return getRandData()
+				return getRandData(r)
 			},
 		},
 		{
 			Params: ast.Identifiers{},
 			Name:   "get_rand_user_agent",
 			Func: func(args []interface{}) (interface{}, error) {
-				ua := getRandUserAgent()
+				ua := getRandUserAgent(r)
 				return ua, nil
 			},
 		},
@@ -0,0 +1,72 @@
+package event
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/metrics"
+)
+
+const seededSchema = `{
+  integer: std.native("get_integer")(1, 1000000),
+  number: std.native("get_number")(1, 10),
+  one_of: std.native("get_one_of")("a, b, c, d"),
+  timestamp: std.native("get_timestamp")("2021-01-01", "2021-12-31", "1s"),
+  data: std.native("get_rand_data")(),
+  user_agent: std.native("get_rand_user_agent")(),
+}`
+
+func TestComposerSeed(t *testing.T) {
+	render := func(seed int64) []byte {
+		composer, err := NewComposerByContent("", "", "test", []byte(seededSchema))
+		if err != nil {
+			t.Fatal("create composer failed", err)
+		}
+		composer.SetSeed(seed)
+		var out bytes.Buffer
+		for i := 0; i < 20; i++ {
+			evt, err := composer.Compose()
+			if err != nil {
+				t.Fatal("compose event failed", err)
+			}
+			out.Write(evt.Json)
+		}
+		return out.Bytes()
+	}
+
+	if !bytes.Equal(render(42), render(42)) {
+		t.Error("the same seed renders different events")
+	}
+	if bytes.Equal(render(42), render(43)) {
+		t.Error("different seeds render the same events")
+	}
+}
+
+func TestComposerMetrics(t *testing.T) {
+	composer, err := NewComposerByContent("", "", "preview", []byte(`{id: 1}`))
+	if err != nil {
+		t.Fatal("create composer failed", err)
+	}
+	_, err = composer.Compose()
+	if err != nil {
+		t.Fatal("compose event failed", err)
+	}
+	if n := testutil.CollectAndCount(metrics.EventsComposed); n != 0 {
+		t.Errorf("composer of preview reports %d series", n)
+	}
+
+	composer.EnableMetrics("42")
+	_, err = composer.Compose()
+	if err != nil {
+		t.Fatal("compose event failed", err)
+	}
+	defer metrics.DeleteGenerator("42")
+	if v := testutil.ToFloat64(metrics.EventsComposed.WithLabelValues("42")); v != 1 {
+		t.Errorf("expected 1 event composed by generator, got %v", v)
+	}
+	if n := testutil.CollectAndCount(metrics.EvaluationDuration); n != 1 {
+		t.Errorf("expected evaluation time of generator, got %d series", n)
+	}
+}
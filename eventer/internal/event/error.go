@@ -0,0 +1,43 @@
+package event
+
+import (
+	"errors"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+)
+
+type SchemaError struct {
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+}
+
+func (e *SchemaError) Error() string {
+	return e.Message
+}
+
+// ToSchemaError extracts location of jsonnet static (parse) or runtime error
+func ToSchemaError(err error) *SchemaError {
+	schemaErr := &SchemaError{Message: err.Error()}
+
+	var loc ast.LocationRange
+	var runtimeErr jsonnet.RuntimeError
+	var staticErr interface{ Loc() ast.LocationRange }
+	if errors.As(err, &runtimeErr) {
+		schemaErr.Message = runtimeErr.Msg
+		for _, frame := range runtimeErr.StackTrace {
+			if frame.Loc.IsSet() {
+				loc = frame.Loc
+				break
+			}
+		}
+	} else if errors.As(err, &staticErr) {
+		loc = staticErr.Loc()
+	}
+	if loc.IsSet() {
+		schemaErr.Line = loc.Begin.Line
+		schemaErr.Column = loc.Begin.Column
+	}
+	return schemaErr
+}
@@ -0,0 +1,33 @@
+package event
+
+import (
+	"testing"
+)
+
+func TestToSchemaError(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		line   int
+		column int
+	}{
+		{"parse", "{\n  id: ,\n}", 2, 7},
+		{"runtime", "{\n  id: error \"boom\",\n}", 2, 7},
+		{"native", "{\n  id: std.native(\"get_integer\")(\"a\", 1),\n}", 2, 7},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			composer, err := NewComposerByContent("", "", "test", []byte(test.schema))
+			if err == nil {
+				_, err = composer.Compose()
+			}
+			if err == nil {
+				t.Fatal("expected schema error")
+			}
+			schemaErr := ToSchemaError(err)
+			if schemaErr.Line != test.line || schemaErr.Column != test.column {
+				t.Errorf("expected location %d:%d, got %d:%d (%s)", test.line, test.column, schemaErr.Line, schemaErr.Column, schemaErr.Message)
+			}
+		})
+	}
+}
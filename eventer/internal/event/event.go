@@ -7,14 +7,26 @@ import (
 const (
 	DestinationTypeKafka    = "kafka"
 	DestinationTypePostgres = "postgres"
+	DestinationTypeHttp     = "http"
 )
 
 type EventDesc struct {
-	Id       string `json:"id"`
-	Dataset  string `json:"dataset"`
-	Schema   []byte `json:"schema"`
-	Count    int64  `json:"count,omitempty"`
-	Interval string `json:"interval,omitempty"`
+	Id                  string          `json:"id"`
+	Dataset             string          `json:"dataset"`
+	Schema              []byte          `json:"schema"`
+	Count               int64           `json:"count,omitempty"`
+	Interval            string          `json:"interval,omitempty"`
+	Rate                *RateDesc       `json:"rate,omitempty"`
+	Throughput          *ThroughputDesc `json:"throughput,omitempty"`
+	Seed                *int64          `json:"seed,omitempty"`
+	MaxFailures         int64           `json:"max_failures,omitempty"`
+	MaxDeliveryFailures int64           `json:"max_delivery_failures,omitempty"`
+}
+
+type ThroughputDesc struct {
+	Rate      float64 `json:"rate,omitempty"`
+	Workers   int     `json:"workers,omitempty"`
+	BatchSize int     `json:"batch_size,omitempty"`
 }
 
 type DestinationDesc struct {
@@ -22,6 +34,7 @@ type DestinationDesc struct {
 	Type     string                 `json:"type"`
 	Kafka    *config.KafkaConfig    `json:"kafka,omitempty"`
 	Postgres *config.PostgresConfig `json:"postgres,omitempty"`
+	Http     *config.HttpConfig     `json:"http,omitempty"`
 }
 
 type ScheduleDesc struct {
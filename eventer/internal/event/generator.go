@@ -3,10 +3,12 @@ package event
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync/atomic"
-	"time"
 
 	"go.uber.org/zap"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/utils"
 )
 
 type EventId []byte
@@ -15,11 +17,20 @@ var NoEventId EventId
 
 type EventObject map[string]interface{}
 
+// DeliveryReporter receives delivery reports of the events sent asynchronously,
+// shared destinations label their metrics by the id of the generator
+type DeliveryReporter interface {
+	GetGeneratorId() string
+	Delivered()
+	Failed(err error)
+}
+
 type Event struct {
-	Id     EventId
-	Json   EventJson
-	Object EventObject
-	IsStop bool
+	Id       EventId
+	Json     EventJson
+	Object   EventObject
+	IsStop   bool
+	Delivery DeliveryReporter
 }
 
 var NoEvent = &Event{}
@@ -39,14 +50,29 @@ func (evt *Event) String() string {
 type Generator struct {
 	event    atomic.Value
 	composer *Composer
+	gate     *utils.Gate
+	consumed chan struct{}
 }
 
-func NewGenerator(ctx context.Context, interval time.Duration, composer *Composer) *Generator {
-	generator := Generator{composer: composer}
-	go generator.run(ctx, interval)
+// NewGenerator composes the next event once the previous one is consumed, so the generator follows the rate of the consumer
+func NewGenerator(ctx context.Context, composer *Composer) *Generator {
+	generator := Generator{
+		composer: composer,
+		gate:     utils.NewGate(),
+		consumed: make(chan struct{}, 1),
+	}
+	go generator.run(ctx)
 	return &generator
 }
 
+func (s *Generator) Pause() bool {
+	return s.gate.Close()
+}
+
+func (s *Generator) Resume() bool {
+	return s.gate.Open()
+}
+
 func (s *Generator) Event(forceUpdate ...bool) *Event {
 	if len(forceUpdate) > 0 && forceUpdate[0] {
 		err := s.generate()
@@ -59,40 +85,48 @@ func (s *Generator) Event(forceUpdate ...bool) *Event {
 	if !ok {
 		return NoEvent
 	}
-	defer s.event.Store(NoEvent)
+	if event.IsValid() {
+		s.event.Store(NoEvent)
+		select {
+		case s.consumed <- struct{}{}:
+		default:
+		}
+	}
 	return event
 }
 
-func (s *Generator) run(ctx context.Context, interval time.Duration) {
-	ticker := time.NewTicker(interval)
+func (s *Generator) run(ctx context.Context) {
 	err := s.generate()
 	if err != nil {
 		zap.L().Error("failed to generate event", zap.Error(err))
 		return
 	}
-	defer ticker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			zap.L().Info("Generator interrupted")
 			return
-		case <-ticker.C:
-			err := s.generate()
-			if err != nil {
-				zap.L().Error("failed to generate event", zap.Error(err))
-				return
-			}
+		case <-s.consumed:
+		}
+		if s.gate.IsClosed() && !s.gate.Wait(ctx) {
+			zap.L().Info("Generator interrupted")
+			return
+		}
+		err := s.generate()
+		if err != nil {
+			zap.L().Error("failed to generate event", zap.Error(err))
+			return
 		}
 	}
 }
 
 func (s *Generator) generate() error {
-	event, obj, err := s.composer.NewEvent()
+	event, err := s.composer.Compose()
 	if err != nil {
 		s.event.Store(StopEvent)
 		return err
 	}
-	s.event.Store(&Event{Json: event, Id: GetId(obj), Object: obj})
+	s.event.Store(event)
 	return nil
 }
 
@@ -112,3 +146,23 @@ func GetId(v EventObject) EventId {
 		return EventId(fmt.Sprint(t))
 	}
 }
+
+// GetPath returns the value of the field by the path of the nested field names separated by dots
+func GetPath(v EventObject, path string) (interface{}, bool) {
+	var obj map[string]interface{} = v
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		value, ok := obj[name]
+		if !ok {
+			return nil, false
+		}
+		if i == len(names)-1 {
+			return value, true
+		}
+		obj, ok = value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+	}
+	return nil, false
+}
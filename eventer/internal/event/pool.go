@@ -0,0 +1,41 @@
+package event
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type Pool struct {
+	events chan *Event
+}
+
+func NewPool(ctx context.Context, composers []*Composer, size int) *Pool {
+	p := &Pool{events: make(chan *Event, size)}
+	for _, composer := range composers {
+		go p.run(ctx, composer)
+	}
+	return p
+}
+
+func (p *Pool) Events() <-chan *Event {
+	return p.events
+}
+
+func (p *Pool) run(ctx context.Context, composer *Composer) {
+	for {
+		event, err := composer.Compose()
+		if err != nil {
+			zap.L().Error("failed to generate event", zap.Error(err))
+			event = StopEvent
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case p.events <- event:
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -1,76 +1,280 @@
 package event
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/bxcodec/faker/v3"
-
-	browser "github.com/EDDYCJY/fake-useragent"
+	// The browser package loads the user agents on init
+	_ "github.com/EDDYCJY/fake-useragent"
+	"github.com/EDDYCJY/fake-useragent/useragent"
 )
 
 type SomeStructWithTags struct {
-	Latitude           float32 `faker:"lat" json:"lat"`
-	Longitude          float32 `faker:"long" json:"long"`
-	CreditCardNumber   string  `faker:"cc_number" json:"cc_number"`
-	CreditCardType     string  `faker:"cc_type" json:"cc_type"`
-	Email              string  `faker:"email" json:"email"`
-	DomainName         string  `faker:"domain_name" json:"domain_name"`
-	IPV4               string  `faker:"ipv4" json:"ipv4"`
-	IPV6               string  `faker:"ipv6" json:"ipv6"`
-	Password           string  `faker:"password" json:"password"`
-	Jwt                string  `faker:"jwt" json:"jwt"`
-	PhoneNumber        string  `faker:"phone_number" json:"phone_number"`
-	MacAddress         string  `faker:"mac_address" json:"mac_address"`
-	URL                string  `faker:"url" json:"url"`
-	UserName           string  `faker:"username" json:"username"`
-	TollFreeNumber     string  `faker:"toll_free_number" json:"toll_free_number"`
-	E164PhoneNumber    string  `faker:"e_164_phone_number" json:"e_164_phone_number"`
-	FirstName          string  `faker:"first_name" json:"first_name"`
-	LastName           string  `faker:"last_name" json:"last_name"`
-	Name               string  `faker:"name" json:"name"`
-	UnixTime           int64   `faker:"unix_time" json:"unix_time"`
-	Date               string  `faker:"date" json:"date"`
-	Time               string  `faker:"time" json:"time"`
-	MonthName          string  `faker:"month_name" json:"month_name"`
-	Year               string  `faker:"year" json:"year"`
-	DayOfWeek          string  `faker:"day_of_week" json:"day_of_week"`
-	DayOfMonth         string  `faker:"day_of_month" json:""`
-	Timestamp          string  `faker:"timestamp" json:"timestamp"`
-	Century            string  `faker:"century" json:"century"`
-	TimeZone           string  `faker:"timezone" json:"timezone"`
-	TimePeriod         string  `faker:"time_period" json:"time_period"`
-	Word               string  `faker:"word" json:"word"`
-	Sentence           string  `faker:"sentence" json:"sentence"`
-	Paragraph          string  `faker:"paragraph" json:"paragraph"`
-	Currency           string  `faker:"currency" json:"currency"`
-	Amount             float64 `faker:"amount" json:"amount"`
-	AmountWithCurrency string  `faker:"amount_with_currency" json:"amount_with_currency"`
-	UUIDHypenated      string  `faker:"uuid_hyphenated" json:"uuid_hyphenated"`
-	UUID               string  `faker:"uuid_digit" json:"uuid_digit"`
-	PaymentMethod      string  `faker:"oneof: cc, paypal, check, money order" json:"payment_method"`
-	ID                 int64   `faker:"oneof: 1, 10000" json:"id"`
-	Price              float64 `faker:"oneof: 1.5, 100.99" json:"price"`
-	Number             int64   `faker:"oneof: 1, 10000" json:"number"`
+	Latitude           float32 `json:"lat"`
+	Longitude          float32 `json:"long"`
+	CreditCardNumber   string  `json:"cc_number"`
+	CreditCardType     string  `json:"cc_type"`
+	Email              string  `json:"email"`
+	DomainName         string  `json:"domain_name"`
+	IPV4               string  `json:"ipv4"`
+	IPV6               string  `json:"ipv6"`
+	Password           string  `json:"password"`
+	Jwt                string  `json:"jwt"`
+	PhoneNumber        string  `json:"phone_number"`
+	MacAddress         string  `json:"mac_address"`
+	URL                string  `json:"url"`
+	UserName           string  `json:"username"`
+	TollFreeNumber     string  `json:"toll_free_number"`
+	E164PhoneNumber    string  `json:"e_164_phone_number"`
+	FirstName          string  `json:"first_name"`
+	LastName           string  `json:"last_name"`
+	Name               string  `json:"name"`
+	UnixTime           int64   `json:"unix_time"`
+	Date               string  `json:"date"`
+	Time               string  `json:"time"`
+	MonthName          string  `json:"month_name"`
+	Year               string  `json:"year"`
+	DayOfWeek          string  `json:"day_of_week"`
+	DayOfMonth         string  `json:""`
+	Timestamp          string  `json:"timestamp"`
+	Century            string  `json:"century"`
+	TimeZone           string  `json:"timezone"`
+	TimePeriod         string  `json:"time_period"`
+	Word               string  `json:"word"`
+	Sentence           string  `json:"sentence"`
+	Paragraph          string  `json:"paragraph"`
+	Currency           string  `json:"currency"`
+	Amount             float64 `json:"amount"`
+	AmountWithCurrency string  `json:"amount_with_currency"`
+	UUIDHypenated      string  `json:"uuid_hyphenated"`
+	UUID               string  `json:"uuid_digit"`
+	PaymentMethod      string  `json:"payment_method"`
+	ID                 int64   `json:"id"`
+	Price              float64 `json:"price"`
+	Number             int64   `json:"number"`
 }
 
-func getRandData() (interface{}, error) {
-	var s SomeStructWithTags
-	err := faker.FakeData(&s)
-	if err != nil {
-		return map[string]interface{}{}, err
+type creditCard struct {
+	ccType   string
+	length   int
+	prefixes []string
+}
+
+var (
+	creditCards = []creditCard{
+		{"VISA", 16, []string{"4539", "4556", "4916", "4532", "4929", "4485", "4716"}},
+		{"MasterCard", 16, []string{"51", "52", "53", "54", "55"}},
+		{"American Express", 15, []string{"34", "37"}},
+		{"Discover", 16, []string{"6011"}},
+		{"JCB", 16, []string{"3528", "3538", "3548", "3558", "3568", "3578", "3588"}},
+		{"Diners Club", 14, []string{"36", "38", "39"}},
+	}
+	titles     = []string{"Mr.", "Mrs.", "Ms.", "Miss", "Dr.", "Prof."}
+	firstNames = []string{
+		"Aaliyah", "Adrian", "Alice", "Andrew", "Bella", "Brandon", "Camila", "Carter", "Chloe", "Daniel",
+		"Eleanor", "Ethan", "Fiona", "Gabriel", "Grace", "Henry", "Isabella", "Jack", "Julia", "Kevin",
+		"Layla", "Liam", "Lucy", "Mason", "Mia", "Noah", "Olivia", "Owen", "Penelope", "Ryan",
+		"Sophia", "Thomas", "Victoria", "William", "Zoe",
 	}
+	lastNames = []string{
+		"Anderson", "Baker", "Brown", "Campbell", "Carter", "Clark", "Davis", "Evans", "Garcia", "Green",
+		"Hall", "Harris", "Jackson", "Johnson", "King", "Lee", "Lewis", "Martin", "Miller", "Mitchell",
+		"Moore", "Nelson", "Parker", "Roberts", "Robinson", "Smith", "Taylor", "Thomas", "Turner", "Walker",
+		"White", "Williams", "Wilson", "Wright", "Young",
+	}
+	words = []string{
+		"alias", "consequatur", "aut", "perferendis", "sit", "voluptatem", "accusantium", "doloremque", "aperiam",
+		"eaque", "ipsa", "quae", "ab", "illo", "inventore", "veritatis", "et", "quasi", "architecto", "beatae",
+		"vitae", "dicta", "sunt", "explicabo", "nemo", "enim", "ipsam", "quia", "voluptas", "aspernatur",
+		"odit", "fugit", "sed", "consequuntur", "magni", "dolores", "eos", "qui", "ratione", "sequi", "nesciunt",
+		"neque", "dolorem", "ipsum", "dolor", "amet", "consectetur", "adipisci", "velit", "numquam",
+	}
+	tlds        = []string{"com", "net", "org", "biz", "info", "ru", "io"}
+	timeZones   = []string{"UTC", "Europe/London", "Europe/Moscow", "Europe/Paris", "America/New_York", "America/Los_Angeles", "Asia/Tokyo", "Asia/Shanghai", "Australia/Sydney", "Africa/Cairo"}
+	currencies  = []string{"USD", "EUR", "GBP", "JPY", "CNY", "RUB", "CHF", "CAD", "AUD", "INR"}
+	centuries   = []string{"I", "II", "III", "IV", "V", "VI", "VII", "VIII", "IX", "X", "XI", "XII", "XIII", "XIV", "XV", "XVI", "XVII", "XVIII", "XIX", "XX", "XXI"}
+	payments    = []string{"cc", "paypal", "check", "money order"}
+	letters     = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	hexDigits   = "0123456789abcdef"
+	maxUnixTime = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+)
+
+var (
+	seedsLock sync.Mutex
+	seeds     = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// newRand makes the random source of the composer, the seed is random if not specified
+func newRand(seed *int64) *rand.Rand {
+	if seed != nil {
+		return rand.New(rand.NewSource(*seed))
+	}
+	seedsLock.Lock()
+	defer seedsLock.Unlock()
+	return rand.New(rand.NewSource(seeds.Int63()))
+}
+
+// getRandData builds the same fields the faker library did, but only from the composer source
+func getRandData(r *rand.Rand) (interface{}, error) {
+	s := newRandStruct(r)
 	b, err := json.Marshal(s)
 	if err != nil {
 		return map[string]interface{}{}, err
 	}
-	var r map[string]interface{}
-	err = json.Unmarshal(b, &r)
+	var m map[string]interface{}
+	err = json.Unmarshal(b, &m)
 	if err != nil {
 		return map[string]interface{}{}, err
 	}
-	return r, nil
+	return m, nil
+}
+
+func newRandStruct(r *rand.Rand) SomeStructWithTags {
+	card := creditCards[r.Intn(len(creditCards))]
+	prefix := card.prefixes[r.Intn(len(card.prefixes))]
+	firstName := oneOf(r, firstNames)
+	lastName := oneOf(r, lastNames)
+	userName := randString(r, letters, 7)
+	domain := fmt.Sprintf("%s.%s", randString(r, letters[:26], 7), oneOf(r, tlds))
+	t := time.Unix(r.Int63n(maxUnixTime), 0).UTC()
+	currency := oneOf(r, currencies)
+	amount := float64(r.Int63n(10000000)) / 100
+	timePeriod := "AM"
+	if t.Hour() >= 12 {
+		timePeriod = "PM"
+	}
+
+	return SomeStructWithTags{
+		Latitude:           float32(r.Float64()*180 - 90),
+		Longitude:          float32(r.Float64()*360 - 180),
+		CreditCardNumber:   prefix + randString(r, hexDigits[:10], card.length-len(prefix)),
+		CreditCardType:     card.ccType,
+		Email:              fmt.Sprintf("%s@%s", userName, domain),
+		DomainName:         domain,
+		IPV4:               fmt.Sprintf("%d.%d.%d.%d", 1+r.Intn(254), r.Intn(256), r.Intn(256), 1+r.Intn(254)),
+		IPV6:               randGroups(r, 8, 4, ":"),
+		Password:           randString(r, letters, 50),
+		Jwt:                randJwt(r),
+		PhoneNumber:        fmt.Sprintf("%d-%s-%s", 201+r.Intn(799), randString(r, hexDigits[:10], 3), randString(r, hexDigits[:10], 4)),
+		MacAddress:         randGroups(r, 6, 2, ":"),
+		URL:                fmt.Sprintf("https://www.%s/", domain),
+		UserName:           userName,
+		TollFreeNumber:     fmt.Sprintf("(%s) %s-%s", oneOf(r, []string{"800", "888", "877", "866", "855"}), randString(r, hexDigits[:10], 3), randString(r, hexDigits[:10], 4)),
+		E164PhoneNumber:    fmt.Sprintf("+%d%s", 1+r.Intn(98), randString(r, hexDigits[:10], 10)),
+		FirstName:          firstName,
+		LastName:           lastName,
+		Name:               fmt.Sprintf("%s %s %s", oneOf(r, titles), firstName, lastName),
+		UnixTime:           t.Unix(),
+		Date:               t.Format("2006-01-02"),
+		Time:               t.Format("15:04:05"),
+		MonthName:          t.Month().String(),
+		Year:               t.Format("2006"),
+		DayOfWeek:          t.Weekday().String(),
+		DayOfMonth:         t.Format("2"),
+		Timestamp:          t.Format("2006-01-02 15:04:05"),
+		Century:            oneOf(r, centuries),
+		TimeZone:           oneOf(r, timeZones),
+		TimePeriod:         timePeriod,
+		Word:               oneOf(r, words),
+		Sentence:           randSentence(r),
+		Paragraph:          randParagraph(r),
+		Currency:           currency,
+		Amount:             amount,
+		AmountWithCurrency: fmt.Sprintf("%s %f", currency, amount),
+		UUIDHypenated:      randUuid(r, true),
+		UUID:               randUuid(r, false),
+		PaymentMethod:      oneOf(r, payments),
+		ID:                 []int64{1, 10000}[r.Intn(2)],
+		Price:              []float64{1.5, 100.99}[r.Intn(2)],
+		Number:             []int64{1, 10000}[r.Intn(2)],
+	}
+}
+
+var (
+	userAgentsOnce sync.Once
+	userAgents     []string
+)
+
+// getRandUserAgent picks one of the user agents loaded by the browser package, they are sorted by browser to keep the order stable
+func getRandUserAgent(r *rand.Rand) string {
+	userAgentsOnce.Do(func() {
+		all := useragent.UA.GetAll()
+		browsers := make([]string, 0, len(all))
+		for browser := range all {
+			browsers = append(browsers, browser)
+		}
+		sort.Strings(browsers)
+		for _, browser := range browsers {
+			userAgents = append(userAgents, all[browser]...)
+		}
+	})
+	if len(userAgents) == 0 {
+		return ""
+	}
+	return userAgents[r.Intn(len(userAgents))]
+}
+
+func oneOf(r *rand.Rand, values []string) string {
+	return values[r.Intn(len(values))]
 }
 
-func getRandUserAgent() string {
-	return browser.Random()
+func randString(r *rand.Rand, alphabet string, n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = alphabet[r.Intn(len(alphabet))]
+	}
+	return string(b)
+}
+
+func randGroups(r *rand.Rand, groups int, size int, sep string) string {
+	parts := make([]string, groups)
+	for i := range parts {
+		parts[i] = randString(r, hexDigits, size)
+	}
+	return strings.Join(parts, sep)
+}
+
+func randUuid(r *rand.Rand, hyphenated bool) string {
+	b := make([]byte, 16)
+	r.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	if !hyphenated {
+		return fmt.Sprintf("%x", b)
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func randJwt(r *rand.Rand) string {
+	encoding := base64.RawURLEncoding
+	header := encoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload := encoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":"%s","iat":%d}`, randString(r, letters, 10), r.Int63n(maxUnixTime))))
+	signature := make([]byte, 32)
+	r.Read(signature)
+	return strings.Join([]string{header, payload, encoding.EncodeToString(signature)}, ".")
+}
+
+func randSentence(r *rand.Rand) string {
+	n := 5 + r.Intn(6)
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = oneOf(r, words)
+	}
+	sentence := strings.Join(parts, " ")
+	return strings.ToUpper(sentence[:1]) + sentence[1:] + "."
+}
+
+func randParagraph(r *rand.Rand) string {
+	n := 3 + r.Intn(4)
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = randSentence(r)
+	}
+	return strings.Join(parts, " ")
 }
@@ -0,0 +1,240 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	RateTypeConstant = "constant"
+	RateTypeRamp     = "ramp"
+	RateTypeStep     = "step"
+	RateTypeSine     = "sine"
+	RateTypePoisson  = "poisson"
+	RateTypeBurst    = "burst"
+)
+
+const (
+	MinInterval  = time.Millisecond
+	idleInterval = 100 * time.Millisecond
+)
+
+type RateStepDesc struct {
+	Rate     float64 `json:"rate"`
+	Duration string  `json:"duration"`
+}
+
+type RateDesc struct {
+	Type      string         `json:"type"`
+	Rate      float64        `json:"rate,omitempty"`
+	From      float64        `json:"from,omitempty"`
+	To        float64        `json:"to,omitempty"`
+	Min       float64        `json:"min,omitempty"`
+	Max       float64        `json:"max,omitempty"`
+	BurstRate float64        `json:"burst_rate,omitempty"`
+	Duration  string         `json:"duration,omitempty"`
+	Period    string         `json:"period,omitempty"`
+	Steps     []RateStepDesc `json:"steps,omitempty"`
+	Repeat    bool           `json:"repeat,omitempty"`
+}
+
+// Profile defines the target rate (events per second) over the time elapsed since the generator start
+type Profile interface {
+	Rate(elapsed time.Duration) float64
+	Next(elapsed time.Duration) time.Duration
+}
+
+// NewProfile makes the rate profile, random intervals are taken from the seeded source if seed is set
+func NewProfile(desc *RateDesc, interval time.Duration, seed *int64) (Profile, error) {
+	if desc == nil {
+		if interval < MinInterval {
+			return nil, fmt.Errorf("interval must be >= %v", MinInterval)
+		}
+		return constantProfile{interval: interval}, nil
+	}
+
+	switch desc.Type {
+	case RateTypeConstant:
+		err := validateRates(desc.Rate)
+		if err != nil {
+			return nil, err
+		}
+		return rateProfile(func(time.Duration) float64 {
+			return desc.Rate
+		}), nil
+	case RateTypeRamp:
+		err := validateRates(desc.From, desc.To)
+		if err != nil {
+			return nil, err
+		}
+		duration, err := parseRateDuration("duration", desc.Duration)
+		if err != nil {
+			return nil, err
+		}
+		return rateProfile(func(elapsed time.Duration) float64 {
+			if elapsed >= duration {
+				return desc.To
+			}
+			return desc.From + (desc.To-desc.From)*float64(elapsed)/float64(duration)
+		}), nil
+	case RateTypeStep:
+		return newStepProfile(desc)
+	case RateTypeSine:
+		err := validateRates(desc.Min, desc.Max)
+		if err != nil {
+			return nil, err
+		}
+		if desc.Min > desc.Max {
+			return nil, errors.New("min rate must be <= max rate")
+		}
+		period, err := parseRateDuration("period", desc.Period)
+		if err != nil {
+			return nil, err
+		}
+		// Starts from the min rate and reaches the max rate in the middle of the period
+		return rateProfile(func(elapsed time.Duration) float64 {
+			phase := 2 * math.Pi * float64(elapsed%period) / float64(period)
+			return desc.Min + (desc.Max-desc.Min)*(1-math.Cos(phase))/2
+		}), nil
+	case RateTypePoisson:
+		err := validateRates(desc.Rate)
+		if err != nil {
+			return nil, err
+		}
+		return poissonProfile{rate: desc.Rate, rand: newRand(seed)}, nil
+	case RateTypeBurst:
+		err := validateRates(desc.Rate, desc.BurstRate)
+		if err != nil {
+			return nil, err
+		}
+		period, err := parseRateDuration("period", desc.Period)
+		if err != nil {
+			return nil, err
+		}
+		duration, err := parseRateDuration("duration", desc.Duration)
+		if err != nil {
+			return nil, err
+		}
+		if duration > period {
+			return nil, errors.New("burst duration must be <= period")
+		}
+		return rateProfile(func(elapsed time.Duration) float64 {
+			if elapsed%period < duration {
+				return desc.BurstRate
+			}
+			return desc.Rate
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown rate type: %s", desc.Type)
+	}
+}
+
+func newStepProfile(desc *RateDesc) (Profile, error) {
+	if len(desc.Steps) == 0 {
+		return nil, errors.New("steps are not specified")
+	}
+	rates := make([]float64, len(desc.Steps))
+	ends := make([]time.Duration, len(desc.Steps))
+	var total time.Duration
+	for i, step := range desc.Steps {
+		err := validateRates(step.Rate)
+		if err != nil {
+			return nil, err
+		}
+		duration, err := parseRateDuration("step duration", step.Duration)
+		if err != nil {
+			return nil, err
+		}
+		total += duration
+		rates[i] = step.Rate
+		ends[i] = total
+	}
+	repeat := desc.Repeat
+	return rateProfile(func(elapsed time.Duration) float64 {
+		if repeat {
+			elapsed %= total
+		}
+		for i, end := range ends {
+			if elapsed < end {
+				return rates[i]
+			}
+		}
+		return rates[len(rates)-1]
+	}), nil
+}
+
+func validateRates(rates ...float64) error {
+	for _, rate := range rates {
+		if rate < 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+			return fmt.Errorf("invalid rate %v, rate must be >= 0", rate)
+		}
+	}
+	return nil
+}
+
+func parseRateDuration(name string, value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s %v: %w", name, value, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be positive", name)
+	}
+	return d, nil
+}
+
+func toInterval(rate float64) time.Duration {
+	if rate <= 0 {
+		return idleInterval
+	}
+	interval := time.Duration(float64(time.Second) / rate)
+	if interval < MinInterval {
+		return MinInterval
+	}
+	return interval
+}
+
+type constantProfile struct {
+	interval time.Duration
+}
+
+func (p constantProfile) Rate(time.Duration) float64 {
+	return float64(time.Second) / float64(p.interval)
+}
+
+func (p constantProfile) Next(time.Duration) time.Duration {
+	return p.interval
+}
+
+type rateProfile func(elapsed time.Duration) float64
+
+func (p rateProfile) Rate(elapsed time.Duration) float64 {
+	return p(elapsed)
+}
+
+func (p rateProfile) Next(elapsed time.Duration) time.Duration {
+	return toInterval(p(elapsed))
+}
+
+type poissonProfile struct {
+	rate float64
+	rand *rand.Rand
+}
+
+func (p poissonProfile) Rate(time.Duration) float64 {
+	return p.rate
+}
+
+func (p poissonProfile) Next(time.Duration) time.Duration {
+	if p.rate <= 0 {
+		return idleInterval
+	}
+	interval := time.Duration(p.rand.ExpFloat64() / p.rate * float64(time.Second))
+	if interval < MinInterval {
+		return MinInterval
+	}
+	return interval
+}
@@ -0,0 +1,99 @@
+package event
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestProfileRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		desc    *RateDesc
+		elapsed time.Duration
+		rate    float64
+	}{
+		{"interval", nil, time.Hour, 4},
+		{"constant", &RateDesc{Type: RateTypeConstant, Rate: 20}, time.Hour, 20},
+		{"ramp start", &RateDesc{Type: RateTypeRamp, From: 10, To: 110, Duration: "100s"}, 0, 10},
+		{"ramp middle", &RateDesc{Type: RateTypeRamp, From: 10, To: 110, Duration: "100s"}, 50 * time.Second, 60},
+		{"ramp end", &RateDesc{Type: RateTypeRamp, From: 10, To: 110, Duration: "100s"}, time.Hour, 110},
+		{"step first", &RateDesc{Type: RateTypeStep, Steps: []RateStepDesc{{1, "1m"}, {5, "1m"}}}, 30 * time.Second, 1},
+		{"step last", &RateDesc{Type: RateTypeStep, Steps: []RateStepDesc{{1, "1m"}, {5, "1m"}}}, time.Hour, 5},
+		{"step repeat", &RateDesc{Type: RateTypeStep, Steps: []RateStepDesc{{1, "1m"}, {5, "1m"}}, Repeat: true}, 2*time.Minute + time.Second, 1},
+		{"sine min", &RateDesc{Type: RateTypeSine, Min: 2, Max: 10, Period: "24h"}, 0, 2},
+		{"sine max", &RateDesc{Type: RateTypeSine, Min: 2, Max: 10, Period: "24h"}, 12 * time.Hour, 10},
+		{"burst on", &RateDesc{Type: RateTypeBurst, Rate: 1, BurstRate: 100, Period: "1m", Duration: "5s"}, time.Minute + time.Second, 100},
+		{"burst off", &RateDesc{Type: RateTypeBurst, Rate: 1, BurstRate: 100, Period: "1m", Duration: "5s"}, 30 * time.Second, 1},
+		{"poisson", &RateDesc{Type: RateTypePoisson, Rate: 50}, time.Hour, 50},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			profile, err := NewProfile(test.desc, 250*time.Millisecond, nil)
+			if err != nil {
+				t.Fatal("make profile failed", err)
+			}
+			rate := profile.Rate(test.elapsed)
+			if math.Abs(rate-test.rate) > 1e-9 {
+				t.Errorf("expected rate %v, got %v", test.rate, rate)
+			}
+		})
+	}
+}
+
+func TestProfileNext(t *testing.T) {
+	profile, err := NewProfile(&RateDesc{Type: RateTypeConstant, Rate: 1e6}, 0, nil)
+	if err != nil {
+		t.Fatal("make profile failed", err)
+	}
+	if next := profile.Next(0); next != MinInterval {
+		t.Errorf("expected interval clamped to %v, got %v", MinInterval, next)
+	}
+
+	profile, err = NewProfile(&RateDesc{Type: RateTypeConstant, Rate: 0}, 0, nil)
+	if err != nil {
+		t.Fatal("make profile failed", err)
+	}
+	if next := profile.Next(0); next != idleInterval {
+		t.Errorf("expected idle interval %v, got %v", idleInterval, next)
+	}
+}
+
+func TestProfileInvalid(t *testing.T) {
+	descs := []*RateDesc{
+		{Type: "unknown"},
+		{Type: RateTypeConstant, Rate: -1},
+		{Type: RateTypeRamp, From: 1, To: 2},
+		{Type: RateTypeStep},
+		{Type: RateTypeSine, Min: 10, Max: 1, Period: "1h"},
+		{Type: RateTypeBurst, Rate: 1, BurstRate: 10, Period: "1s", Duration: "1m"},
+	}
+	for _, desc := range descs {
+		_, err := NewProfile(desc, 0, nil)
+		if err == nil {
+			t.Errorf("expected error for %+v", desc)
+		}
+	}
+	_, err := NewProfile(nil, time.Microsecond, nil)
+	if err == nil {
+		t.Error("expected error for too short interval")
+	}
+}
+
+func TestProfilePoissonSeed(t *testing.T) {
+	seed := int64(42)
+	desc := &RateDesc{Type: RateTypePoisson, Rate: 10}
+	first, err := NewProfile(desc, 0, &seed)
+	if err != nil {
+		t.Fatal("make profile failed", err)
+	}
+	second, err := NewProfile(desc, 0, &seed)
+	if err != nil {
+		t.Fatal("make profile failed", err)
+	}
+	for i := 0; i < 10; i++ {
+		if a, b := first.Next(0), second.Next(0); a != b {
+			t.Fatalf("the same seed makes different intervals %v and %v", a, b)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package generator
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// deliveryReport counts delivery reports of the events sent by the generator and pauses it
+// after too many consecutive delivery failures
+type deliveryReport struct {
+	id          uint64
+	delivered   int64
+	failed      int64
+	consecutive int64
+	maxFailures int64
+	pause       func() bool
+	lock        sync.Mutex
+	lastError   string
+}
+
+func (r *deliveryReport) GetGeneratorId() string {
+	return fmt.Sprint(r.id)
+}
+
+func (r *deliveryReport) Delivered() {
+	atomic.AddInt64(&r.delivered, 1)
+	atomic.StoreInt64(&r.consecutive, 0)
+}
+
+func (r *deliveryReport) Failed(err error) {
+	atomic.AddInt64(&r.failed, 1)
+	consecutive := atomic.AddInt64(&r.consecutive, 1)
+	r.lock.Lock()
+	r.lastError = err.Error()
+	r.lock.Unlock()
+	if r.maxFailures > 0 && consecutive >= r.maxFailures {
+		// Count starts over, so the resumed generator is paused again only after new failures
+		atomic.StoreInt64(&r.consecutive, 0)
+		if r.pause() {
+			zap.L().Error("Too many consecutive delivery failures, generator is paused.", zap.Uint64("id", r.id), zap.Int64("failures", consecutive))
+		}
+	}
+}
+
+func (r *deliveryReport) get() (int64, int64, string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return atomic.LoadInt64(&r.delivered), atomic.LoadInt64(&r.failed), r.lastError
+}
+
+// writeReport counts the events written by the buffered destination as sent or failed ones,
+// so the generator is stopped after too many consecutive failed writes the same way as for sends
+type writeReport struct {
+	generator *Generator
+}
+
+func (r writeReport) GetGeneratorId() string {
+	return r.generator.GetId()
+}
+
+func (r writeReport) Delivered() {
+	r.generator.addSent(1)
+}
+
+func (r writeReport) Failed(err error) {
+	if r.generator.addFailed(1, err) {
+		r.generator.abort()
+	}
+}
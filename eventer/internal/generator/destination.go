@@ -7,7 +7,25 @@ import (
 type Destinaton interface {
 	Init(evt *event.Event) error
 	GetId() uint64
+	GetType() string
+	GetTarget() string
 	Send(evt *event.Event) error
 	Flush()
 	Close()
 }
+
+// GeneratorDestinaton makes the destination dedicated to the generator when its state can't be shared,
+// nil destination means the shared one is used
+type GeneratorDestinaton interface {
+	ForGenerator(generatorId uint64) (Destinaton, error)
+}
+
+type BatchDestinaton interface {
+	SendBatch(evts []*event.Event) error
+}
+
+// BufferedDestinaton writes the sent events later and reports the result of every event to its delivery reporter,
+// so the generator counts the events as sent or failed once they're written
+type BufferedDestinaton interface {
+	IsBuffered() bool
+}
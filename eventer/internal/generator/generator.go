@@ -2,21 +2,104 @@ package generator
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sibedge-llc/dp-services/eventer/internal/event"
+	"github.com/sibedge-llc/dp-services/eventer/internal/metrics"
+	"github.com/sibedge-llc/dp-services/eventer/internal/utils"
 	"go.uber.org/zap"
 )
 
+type State int
+
+const (
+	StateActive State = iota
+	StatePaused
+	StateStopped
+)
+
+var stateNames = map[State]string{
+	StateActive:  "active",
+	StatePaused:  "paused",
+	StateStopped: "stopped",
+}
+
+func ParseState(name string) (State, error) {
+	for state, stateName := range stateNames {
+		if stateName == name {
+			return state, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown generator state: %s", name)
+}
+
+func (s State) String() string {
+	name, ok := stateNames[s]
+	if !ok {
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+	return name
+}
+
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *State) UnmarshalText(text []byte) error {
+	state, err := ParseState(string(text))
+	if err != nil {
+		return err
+	}
+	*s = state
+	return nil
+}
+
+type Update struct {
+	Interval *time.Duration
+	Rate     *event.RateDesc
+	Count    *int64
+	Dataset  *string
+}
+
 type Generator struct {
 	id          uint64
+	eventId     string
+	lock        sync.Mutex
+	dataset     string
+	interval    time.Duration
+	rate        *event.RateDesc
+	seed        *int64
+	profile     event.Profile
+	started     time.Time
+	paused      time.Time
+	composers   []*event.Composer
 	generator   *event.Generator
+	pool        *event.Pool
+	batchSize   int
+	meter       *rateMeter
+	sent        prometheus.Counter
+	sendErrors  prometheus.Counter
+	noEvents    prometheus.Counter
 	destination Destinaton
+	dedicated   bool
+	buffered    bool
 	cancel      context.CancelFunc
+	abort       context.CancelFunc
 	count       int64
-	isInfinite  bool
+	isInfinite  int32
+	failures    int64
+	consecutive int64
+	maxFailures int64
+	lastError   string
+	delivery    *deliveryReport
+	gate        *utils.Gate
+	updates     chan func() bool
+	stopped     chan struct{}
 }
 
 func NewGenerator(
@@ -32,14 +115,27 @@ func NewGenerator(
 	if err != nil {
 		return nil, fmt.Errorf("failed to create composed based on schema: %w", err)
 	}
+	composer.EnableMetrics(name)
+	if eventDesc.Seed != nil {
+		composer.SetSeed(*eventDesc.Seed)
+	}
 
-	interval, err := time.ParseDuration(eventDesc.Interval)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse time interval %v: %w", eventDesc.Interval, err)
+	throughput := eventDesc.Throughput
+	if throughput != nil && throughput.Rate > 0 && eventDesc.Rate == nil {
+		eventDesc.Rate = &event.RateDesc{Type: event.RateTypeConstant, Rate: throughput.Rate}
 	}
 
-	if interval < time.Millisecond {
-		return nil, fmt.Errorf("interval must be >= 1ms")
+	var interval time.Duration
+	if eventDesc.Rate == nil {
+		interval, err = time.ParseDuration(eventDesc.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse time interval %v: %w", eventDesc.Interval, err)
+		}
+	}
+
+	profile, err := event.NewProfile(eventDesc.Rate, interval, eventDesc.Seed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make rate profile: %w", err)
 	}
 
 	ctx, ctxCancel := context.WithCancel(ctx)
@@ -48,26 +144,85 @@ func NewGenerator(
 		ctxCancel()
 		<-stopped
 	}
-	if eventDesc.Count < -1 {
-		eventDesc.Count = -1
-	}
 
 	s := &Generator{
 		id:          generatorId,
-		generator:   event.NewGenerator(ctx, interval, composer),
+		eventId:     eventDesc.Id,
+		dataset:     eventDesc.Dataset,
+		interval:    interval,
+		rate:        eventDesc.Rate,
+		seed:        eventDesc.Seed,
+		profile:     profile,
+		started:     time.Now(),
+		composers:   []*event.Composer{composer},
+		meter:       newRateMeter(),
+		sent:        metrics.EventsSent.WithLabelValues(name, destination.GetType(), destination.GetTarget()),
+		sendErrors:  metrics.SendErrors.WithLabelValues(name, destination.GetType(), destination.GetTarget()),
+		noEvents:    metrics.NoEventTicks.WithLabelValues(name),
 		destination: destination,
+		maxFailures: eventDesc.MaxFailures,
 		cancel:      cancel,
-		count:       eventDesc.Count,
-		isInfinite:  eventDesc.Count <= 0,
+		abort:       ctxCancel,
+		gate:        utils.NewGate(),
+		updates:     make(chan func() bool),
+		stopped:     stopped,
+	}
+	s.delivery = &deliveryReport{
+		id:          generatorId,
+		maxFailures: eventDesc.MaxDeliveryFailures,
+		pause:       s.Pause,
+	}
+	s.setCount(eventDesc.Count)
+
+	var evt *event.Event
+	if throughput != nil {
+		evt, err = s.initThroughput(ctx, instanceId, eventDesc)
+		if err != nil {
+			ctxCancel()
+			return nil, err
+		}
+	} else {
+		// Sample is composed before the generator starts to keep the sequence of seeded events stable
+		evt, err = composer.Compose()
+		if err != nil {
+			ctxCancel()
+			return nil, fmt.Errorf("failed to compose event: %w", err)
+		}
+		s.generator = event.NewGenerator(ctx, composer)
+	}
+
+	// Dedicated destination belongs to the generator, so it's closed once the generator is stopped
+	if d, ok := destination.(GeneratorDestinaton); ok {
+		dedicated, err := d.ForGenerator(generatorId)
+		if err != nil {
+			ctxCancel()
+			return nil, fmt.Errorf("failed to make destination of generator: %w", err)
+		}
+		if dedicated != nil {
+			destination = dedicated
+			s.destination = dedicated
+			s.dedicated = true
+		}
+	}
+
+	if d, ok := destination.(BufferedDestinaton); ok {
+		s.buffered = d.IsBuffered()
 	}
 
-	evt := s.generator.Event(true)
 	err = destination.Init(evt)
 	if err != nil {
+		ctxCancel()
+		if s.dedicated {
+			destination.Close()
+		}
 		return nil, fmt.Errorf("failed to init destination along event schema: %w", err)
 	}
 
-	go s.run(ctx, interval, stopped)
+	if s.pool != nil {
+		go s.runThroughput(ctx)
+	} else {
+		go s.run(ctx)
+	}
 
 	return s, nil
 }
@@ -76,51 +231,295 @@ func (s *Generator) GetId() string {
 	return fmt.Sprint(s.id)
 }
 
-func (s *Generator) run(ctx context.Context, interval time.Duration, stopped chan struct{}) {
-	ticker := time.NewTicker(interval)
+func (s *Generator) GetEventId() string {
+	return s.eventId
+}
+
+func (s *Generator) GetDataset() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.dataset
+}
+
+func (s *Generator) GetInterval() time.Duration {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.interval
+}
+
+func (s *Generator) GetRate() *event.RateDesc {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.rate
+}
+
+func (s *Generator) GetTargetRate() float64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.profile.Rate(s.elapsed())
+}
+
+func (s *Generator) GetAchievedRate() float64 {
+	return s.meter.Rate()
+}
+
+func (s *Generator) GetThroughput() *event.ThroughputDesc {
+	if s.pool == nil {
+		return nil
+	}
+	return &event.ThroughputDesc{
+		Workers:   len(s.composers),
+		BatchSize: s.batchSize,
+	}
+}
+
+func (s *Generator) GetDestination() Destinaton {
+	return s.destination
+}
+
+func (s *Generator) GetState() State {
+	if s.IsStopped() {
+		return StateStopped
+	}
+	if s.gate.IsClosed() {
+		return StatePaused
+	}
+	return StateActive
+}
+
+func (s *Generator) Pause() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.gate.Close() {
+		return false
+	}
+	s.paused = time.Now()
+	if s.generator != nil {
+		s.generator.Pause()
+	}
+	return true
+}
+
+func (s *Generator) Resume() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.gate.Open() {
+		return false
+	}
+	// The rate profile goes on from where it was paused
+	s.started = s.started.Add(time.Since(s.paused))
+	s.paused = time.Time{}
+	if s.generator != nil {
+		s.generator.Resume()
+	}
+	return true
+}
+
+// elapsed returns the time the generator has been active since the rate profile start, it's called under the lock
+func (s *Generator) elapsed() time.Duration {
+	if !s.paused.IsZero() {
+		return s.paused.Sub(s.started)
+	}
+	return time.Since(s.started)
+}
+
+func (s *Generator) Update(update Update) error {
+	if update.Interval != nil && update.Rate != nil {
+		return errors.New("interval and rate cannot be changed at once")
+	}
+
+	var (
+		interval time.Duration
+		profile  event.Profile
+		err      error
+	)
+	if update.Interval != nil || update.Rate != nil {
+		if update.Interval != nil {
+			interval = *update.Interval
+		}
+		profile, err = event.NewProfile(update.Rate, interval, s.seed)
+		if err != nil {
+			return fmt.Errorf("failed to make rate profile: %w", err)
+		}
+	}
+
+	// Changes are applied by the run loop between ticks to not race with sending events
+	done := make(chan struct{})
+	apply := func() bool {
+		defer close(done)
+		if profile != nil {
+			s.lock.Lock()
+			s.interval = interval
+			s.rate = update.Rate
+			s.profile = profile
+			s.started = time.Now()
+			if !s.paused.IsZero() {
+				s.paused = s.started
+			}
+			s.lock.Unlock()
+		}
+		if update.Count != nil {
+			s.setCount(*update.Count)
+		}
+		if update.Dataset != nil {
+			for _, composer := range s.composers {
+				composer.SetDataset(*update.Dataset)
+			}
+			s.lock.Lock()
+			s.dataset = *update.Dataset
+			s.lock.Unlock()
+		}
+		return profile != nil
+	}
+
+	select {
+	case s.updates <- apply:
+	case <-s.stopped:
+		return ErrorStopped
+	}
+	<-done
+	return nil
+}
+
+func (s *Generator) run(ctx context.Context) {
+	timer := time.NewTimer(s.nextInterval())
 	defer func() {
-		ticker.Stop()
+		timer.Stop()
 		s.destination.Flush()
-		close(stopped)
-		atomic.StoreInt64((*int64)(&s.count), -2)
+		if s.dedicated {
+			s.destination.Close()
+		}
+		atomic.StoreInt64(&s.count, -2)
+		close(s.stopped)
 	}()
-	for s.Next() {
+	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
+		case apply := <-s.updates:
+			if apply() {
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(s.nextInterval())
+			}
+		case <-timer.C:
+			timer.Reset(s.nextInterval())
+			if s.gate.IsClosed() {
+				continue
+			}
 			evt := s.generator.Event()
 			if !evt.IsValid() {
 				if evt.IsStop {
 					zap.L().Info("Stop event.")
 					return
 				}
-				s.destination.Flush()
+				s.noEvents.Inc()
 				zap.L().Info("No event.")
 				continue
 			}
+			if !s.Next() {
+				return
+			}
+			evt.Delivery = s.reporter()
 			err := s.destination.Send(evt)
 			if err != nil {
-				zap.L().Error("send event to kafka failed.", zap.Error(err))
+				zap.L().Error("send event failed.", zap.Error(err))
+				if s.addFailed(1, err) {
+					return
+				}
+			} else if !s.buffered {
+				s.addSent(1)
+			}
+			if s.IsStopped() {
+				return
 			}
 		}
 	}
 }
 
+// reporter returns the reporter of the sent events, the events written later by the buffered destination
+// are counted by their reports instead of the sends
+func (s *Generator) reporter() event.DeliveryReporter {
+	if s.buffered {
+		return writeReport{generator: s}
+	}
+	return s.delivery
+}
+
+func (s *Generator) addSent(n int) {
+	s.meter.Add(n)
+	s.sent.Add(float64(n))
+	atomic.StoreInt64(&s.consecutive, 0)
+}
+
+// addFailed counts failed events and tells whether the generator has to stop after too many consecutive failures
+func (s *Generator) addFailed(n int, err error) bool {
+	s.sendErrors.Add(float64(n))
+	atomic.AddInt64(&s.failures, int64(n))
+	consecutive := atomic.AddInt64(&s.consecutive, int64(n))
+	s.lock.Lock()
+	s.lastError = err.Error()
+	s.lock.Unlock()
+	if s.maxFailures > 0 && consecutive >= s.maxFailures {
+		// Failures reported after the limit is reached are not logged again
+		if consecutive-int64(n) < s.maxFailures {
+			zap.L().Error("Too many consecutive send failures, generator is stopped.", zap.Uint64("id", s.id), zap.Int64("failures", consecutive))
+		}
+		return true
+	}
+	return false
+}
+
+// GetFailures returns the number of failed events, the number of consecutive ones and the last error
+func (s *Generator) GetFailures() (int64, int64, string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return atomic.LoadInt64(&s.failures), atomic.LoadInt64(&s.consecutive), s.lastError
+}
+
+// GetDeliveries returns the numbers of delivered and undelivered events and the last delivery error,
+// they are reported by the destinations sending events asynchronously
+func (s *Generator) GetDeliveries() (int64, int64, string) {
+	return s.delivery.get()
+}
+
+func (s *Generator) nextInterval() time.Duration {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.profile.Next(s.elapsed())
+}
+
+func (s *Generator) setCount(count int64) {
+	if count < -1 {
+		count = -1
+	}
+	if count <= 0 {
+		atomic.StoreInt32(&s.isInfinite, 1)
+		atomic.StoreInt64(&s.count, count)
+		return
+	}
+	atomic.StoreInt64(&s.count, count)
+	atomic.StoreInt32(&s.isInfinite, 0)
+}
+
 func (s *Generator) GetStatus() (int64, bool) {
-	count := atomic.LoadInt64((*int64)(&s.count))
-	return count, s.isInfinite
+	count := atomic.LoadInt64(&s.count)
+	return count, atomic.LoadInt32(&s.isInfinite) == 1
 }
 
 func (s *Generator) Next() bool {
-	count := atomic.LoadInt64((*int64)(&s.count))
+	count, isInfinite := s.GetStatus()
 	if count < -1 {
 		return false
 	}
-	if s.isInfinite {
+	if isInfinite {
 		return true
 	}
-	count = atomic.AddInt64((*int64)(&s.count), -1)
+	count = atomic.AddInt64(&s.count, -1)
 	return count >= 0
 }
 
@@ -0,0 +1,342 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/event"
+)
+
+type testDestination struct {
+	lock        sync.Mutex
+	events      []*event.Event
+	err         error
+	deliveryErr error
+}
+
+func (d *testDestination) Init(evt *event.Event) error { return nil }
+func (d *testDestination) GetId() uint64               { return 1 }
+func (d *testDestination) GetType() string             { return "test" }
+func (d *testDestination) GetTarget() string           { return "test" }
+func (d *testDestination) Flush()                      {}
+func (d *testDestination) Close()                      {}
+
+func (d *testDestination) Send(evt *event.Event) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.err != nil {
+		return d.err
+	}
+	d.events = append(d.events, evt)
+	if d.deliveryErr != nil {
+		evt.Delivery.Failed(d.deliveryErr)
+	} else {
+		evt.Delivery.Delivered()
+	}
+	return nil
+}
+
+func (d *testDestination) Count() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return len(d.events)
+}
+
+func TestGeneratorUpdate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	destination := &testDestination{}
+	g, err := NewGenerator(ctx, "instance", 1, event.EventDesc{
+		Id:       "e1",
+		Dataset:  "d1",
+		Schema:   []byte(`{id: std.native("get_dataset")("none")}`),
+		Count:    -1,
+		Interval: "1h",
+	}, destination)
+	if err != nil {
+		t.Fatal("create generator failed", err)
+	}
+	defer g.Stop()
+
+	interval := 2 * time.Millisecond
+	count := int64(3)
+	dataset := "d2"
+	err = g.Update(Update{Interval: &interval, Count: &count, Dataset: &dataset})
+	if err != nil {
+		t.Fatal("update generator failed", err)
+	}
+	if g.GetInterval() != interval || g.GetDataset() != dataset {
+		t.Errorf("unexpected interval %v or dataset %v", g.GetInterval(), g.GetDataset())
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for g.GetState() != StateStopped && time.Now().Before(deadline) {
+		time.Sleep(interval)
+	}
+	if g.GetState() != StateStopped {
+		t.Fatal("generator is not stopped after count is exhausted")
+	}
+	if destination.Count() != int(count) {
+		t.Errorf("expected %d events, got %d", count, destination.Count())
+	}
+	if err := g.Update(Update{Count: &count}); err != ErrorStopped {
+		t.Errorf("expected stopped error, got %v", err)
+	}
+}
+
+func TestGeneratorPause(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	destination := &testDestination{}
+	g, err := NewGenerator(ctx, "instance", 1, event.EventDesc{
+		Id:       "e1",
+		Schema:   []byte(`{id: 1}`),
+		Interval: "1ms",
+	}, destination)
+	if err != nil {
+		t.Fatal("create generator failed", err)
+	}
+	defer g.Stop()
+
+	if !g.Pause() || g.GetState() != StatePaused {
+		t.Fatal("generator is not paused")
+	}
+	sent := destination.Count()
+	time.Sleep(20 * time.Millisecond)
+	if destination.Count() > sent+1 {
+		t.Errorf("paused generator keeps sending: %d -> %d", sent, destination.Count())
+	}
+
+	if !g.Resume() || g.GetState() != StateActive {
+		t.Fatal("generator is not resumed")
+	}
+	deadline := time.Now().Add(time.Second)
+	for destination.Count() <= sent+1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if destination.Count() <= sent+1 {
+		t.Error("resumed generator doesn't send events")
+	}
+}
+
+func TestGeneratorPauseRate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	g, err := NewGenerator(ctx, "instance", 1, event.EventDesc{
+		Id:     "e1",
+		Schema: []byte(`{id: 1}`),
+		Rate:   &event.RateDesc{Type: event.RateTypeRamp, From: 1, To: 1001, Duration: "1s"},
+	}, &testDestination{})
+	if err != nil {
+		t.Fatal("create generator failed", err)
+	}
+	defer g.Stop()
+
+	g.Pause()
+	rate := g.GetTargetRate()
+	time.Sleep(50 * time.Millisecond)
+	if g.GetTargetRate() != rate {
+		t.Errorf("target rate moves while paused: %v -> %v", rate, g.GetTargetRate())
+	}
+	g.Resume()
+	if resumed := g.GetTargetRate(); resumed < rate || resumed > rate+20 {
+		t.Errorf("target rate jumps on resume: %v -> %v", rate, resumed)
+	}
+}
+
+func TestGeneratorThroughput(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	destination := &testDestination{}
+	g, err := NewGenerator(ctx, "instance", 1, event.EventDesc{
+		Id:         "e1",
+		Schema:     []byte(`{id: std.native("get_integer")(0, 1000)}`),
+		Count:      500,
+		Throughput: &event.ThroughputDesc{Rate: 5000, Workers: 2, BatchSize: 50},
+	}, destination)
+	if err != nil {
+		t.Fatal("create generator failed", err)
+	}
+	defer g.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for g.GetState() != StateStopped && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if g.GetState() != StateStopped {
+		t.Fatal("generator is not stopped after count is exhausted")
+	}
+	if destination.Count() != 500 {
+		t.Errorf("expected 500 events, got %d", destination.Count())
+	}
+}
+
+func TestGeneratorMaxFailures(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	destination := &testDestination{err: errors.New("unavailable")}
+	g, err := NewGenerator(ctx, "instance", 1, event.EventDesc{
+		Id:          "e1",
+		Schema:      []byte(`{id: 1}`),
+		Interval:    "1ms",
+		MaxFailures: 3,
+	}, destination)
+	if err != nil {
+		t.Fatal("create generator failed", err)
+	}
+	defer g.Stop()
+
+	deadline := time.Now().Add(time.Second)
+	for g.GetState() != StateStopped && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if g.GetState() != StateStopped {
+		t.Fatal("generator is not stopped after consecutive failures")
+	}
+	failures, consecutive, lastError := g.GetFailures()
+	if failures != 3 || consecutive != 3 || lastError != "unavailable" {
+		t.Errorf("unexpected failures %d, consecutive %d, last error %s", failures, consecutive, lastError)
+	}
+}
+
+// bufferedDestination reports the events once a pair of them is written
+type bufferedDestination struct {
+	testDestination
+	pending []*event.Event
+}
+
+func (d *bufferedDestination) IsBuffered() bool { return true }
+
+func (d *bufferedDestination) Send(evt *event.Event) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.pending = append(d.pending, evt)
+	if len(d.pending) < 2 {
+		return nil
+	}
+	for _, evt := range d.pending {
+		if d.err != nil {
+			evt.Delivery.Failed(d.err)
+		} else {
+			d.events = append(d.events, evt)
+			evt.Delivery.Delivered()
+		}
+	}
+	d.pending = nil
+	return nil
+}
+
+func TestGeneratorBufferedFailures(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	destination := &bufferedDestination{testDestination: testDestination{err: errors.New("unavailable")}}
+	g, err := NewGenerator(ctx, "instance", 1, event.EventDesc{
+		Id:          "e1",
+		Schema:      []byte(`{id: 1}`),
+		Interval:    "1ms",
+		MaxFailures: 3,
+	}, destination)
+	if err != nil {
+		t.Fatal("create generator failed", err)
+	}
+	defer g.Stop()
+
+	deadline := time.Now().Add(time.Second)
+	for g.GetState() != StateStopped && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if g.GetState() != StateStopped {
+		t.Fatal("generator is not stopped after consecutive failed writes")
+	}
+	// Buffered events are not counted as sent, so the failures of both events of every write are consecutive
+	failures, consecutive, lastError := g.GetFailures()
+	if failures < 4 || failures%2 != 0 || consecutive != failures || lastError != "unavailable" {
+		t.Errorf("unexpected failures %d, consecutive %d, last error %s", failures, consecutive, lastError)
+	}
+}
+
+func TestGeneratorMaxDeliveryFailures(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	destination := &testDestination{deliveryErr: errors.New("message too large")}
+	g, err := NewGenerator(ctx, "instance", 1, event.EventDesc{
+		Id:                  "e1",
+		Schema:              []byte(`{id: 1}`),
+		Interval:            "1ms",
+		MaxDeliveryFailures: 3,
+	}, destination)
+	if err != nil {
+		t.Fatal("create generator failed", err)
+	}
+	defer g.Stop()
+
+	deadline := time.Now().Add(time.Second)
+	for g.GetState() != StatePaused && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if g.GetState() != StatePaused {
+		t.Fatal("generator is not paused after consecutive delivery failures")
+	}
+	delivered, failed, lastError := g.GetDeliveries()
+	if delivered != 0 || failed != 3 || lastError != "message too large" || destination.Count() != 3 {
+		t.Errorf("unexpected delivered %d, failed %d, last error %s", delivered, failed, lastError)
+	}
+	failures, _, _ := g.GetFailures()
+	if failures != 0 {
+		t.Errorf("unexpected send failures %d", failures)
+	}
+}
+
+type testClosingDestination struct {
+	testDestination
+	closed chan struct{}
+}
+
+func (d *testClosingDestination) Close() { close(d.closed) }
+
+type testSharedDestination struct {
+	testDestination
+	dedicated *testClosingDestination
+}
+
+func (d *testSharedDestination) ForGenerator(generatorId uint64) (Destinaton, error) {
+	return d.dedicated, nil
+}
+
+func TestGeneratorDedicatedDestination(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	destination := &testSharedDestination{dedicated: &testClosingDestination{closed: make(chan struct{})}}
+	g, err := NewGenerator(ctx, "instance", 1, event.EventDesc{
+		Id:       "e1",
+		Schema:   []byte(`{id: 1}`),
+		Interval: "1ms",
+		Count:    2,
+	}, destination)
+	if err != nil {
+		t.Fatal("create generator failed", err)
+	}
+
+	select {
+	case <-destination.dedicated.closed:
+	case <-time.After(time.Second):
+		t.Fatal("dedicated destination is not closed after generator is stopped")
+	}
+	if destination.Count() != 0 || destination.dedicated.Count() != 2 {
+		t.Errorf("expected events sent to dedicated destination, shared %d, dedicated %d", destination.Count(), destination.dedicated.Count())
+	}
+	if g.GetDestination() != destination.dedicated {
+		t.Error("generator destination is not dedicated one")
+	}
+}
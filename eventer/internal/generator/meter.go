@@ -0,0 +1,43 @@
+package generator
+
+import (
+	"sync"
+	"time"
+)
+
+const rateMeterWindow = time.Second
+
+type rateMeter struct {
+	lock    sync.Mutex
+	started time.Time
+	count   int64
+	rate    float64
+}
+
+func newRateMeter() *rateMeter {
+	return &rateMeter{started: time.Now()}
+}
+
+func (m *rateMeter) Add(n int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.roll(time.Now())
+	m.count += int64(n)
+}
+
+func (m *rateMeter) Rate() float64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.roll(time.Now())
+	return m.rate
+}
+
+func (m *rateMeter) roll(now time.Time) {
+	elapsed := now.Sub(m.started)
+	if elapsed < rateMeterWindow {
+		return
+	}
+	m.rate = float64(m.count) / elapsed.Seconds()
+	m.count = 0
+	m.started = now
+}
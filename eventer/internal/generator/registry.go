@@ -0,0 +1,245 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/event"
+	"github.com/sibedge-llc/dp-services/eventer/internal/utils"
+)
+
+type Registration struct {
+	Id          uint64                `json:"id,string"`
+	Event       event.EventDesc       `json:"event"`
+	Destination event.DestinationDesc `json:"destination"`
+	Count       int64                 `json:"count"`
+	Paused      bool                  `json:"paused,omitempty"`
+	Interval    string                `json:"interval,omitempty"`
+	Rate        *event.RateDesc       `json:"rate,omitempty"`
+	Dataset     *string               `json:"dataset,omitempty"`
+	Redacted    bool                  `json:"redacted,omitempty"`
+}
+
+func (r *Registration) capture(generator *Generator) {
+	count, isInfinite := generator.GetStatus()
+	if isInfinite {
+		count = -1
+	}
+	dataset := generator.GetDataset()
+	r.Count = count
+	r.Paused = generator.GetState() == StatePaused
+	r.Rate = generator.GetRate()
+	if r.Rate == nil {
+		r.Interval = generator.GetInterval().String()
+	} else {
+		r.Interval = ""
+	}
+	r.Dataset = &dataset
+}
+
+func (r *Registration) apply(eventDesc *event.EventDesc) {
+	eventDesc.Count = r.Count
+	if r.Rate != nil {
+		eventDesc.Rate = r.Rate
+	} else if r.Interval != "" {
+		eventDesc.Rate = nil
+		eventDesc.Interval = r.Interval
+		if eventDesc.Throughput != nil {
+			throughput := *eventDesc.Throughput
+			throughput.Rate = 0
+			eventDesc.Throughput = &throughput
+		}
+	}
+	if r.Dataset != nil {
+		eventDesc.Dataset = *r.Dataset
+	}
+}
+
+// redact drops the destination credentials from the registration
+func (r *Registration) redact() {
+	destination := r.Destination
+	if destination.Postgres != nil && destination.Postgres.Password != "" {
+		postgres := *destination.Postgres
+		postgres.Password = ""
+		destination.Postgres = &postgres
+		r.Redacted = true
+	}
+	if destination.Kafka != nil {
+		kafka := *destination.Kafka
+		if kafka.Sasl != nil && kafka.Sasl.Password != "" {
+			sasl := *kafka.Sasl
+			sasl.Password = ""
+			kafka.Sasl = &sasl
+			r.Redacted = true
+		}
+		if kafka.Ssl != nil && kafka.Ssl.KeyPassword != "" {
+			ssl := *kafka.Ssl
+			ssl.KeyPassword = ""
+			kafka.Ssl = &ssl
+			r.Redacted = true
+		}
+		if kafka.SchemaRegistry != nil && kafka.SchemaRegistry.Password != "" {
+			schemaRegistry := *kafka.SchemaRegistry
+			schemaRegistry.Password = ""
+			kafka.SchemaRegistry = &schemaRegistry
+			r.Redacted = true
+		}
+		properties := make(map[string]interface{}, len(kafka.Properties))
+		for key, value := range kafka.Properties {
+			if isSecretProperty(key) {
+				r.Redacted = true
+				continue
+			}
+			properties[key] = value
+		}
+		if len(properties) != len(kafka.Properties) {
+			kafka.Properties = properties
+		}
+		destination.Kafka = &kafka
+	}
+	if destination.Http != nil {
+		http := *destination.Http
+		if http.AuthToken != "" {
+			http.AuthToken = ""
+			r.Redacted = true
+		}
+		headers := make(map[string]string, len(http.Headers))
+		for key, value := range http.Headers {
+			if isSecretHeader(key) {
+				r.Redacted = true
+				continue
+			}
+			headers[key] = value
+		}
+		if len(headers) != len(http.Headers) {
+			http.Headers = headers
+		}
+		destination.Http = &http
+	}
+	r.Destination = destination
+}
+
+func isSecretProperty(key string) bool {
+	return strings.Contains(key, "password") || strings.Contains(key, "secret") || key == "ssl.key.pem"
+}
+
+func isSecretHeader(key string) bool {
+	key = strings.ToLower(key)
+	return key == "authorization" || key == "proxy-authorization" || key == "cookie" || strings.Contains(key, "token") || strings.Contains(key, "api-key")
+}
+
+type Registry interface {
+	Load() ([]Registration, error)
+	Save(registrations ...Registration) error
+	Delete(id uint64) error
+	Close()
+}
+
+type FileRegistry struct {
+	lock          sync.Mutex
+	path          string
+	registrations map[uint64]Registration
+}
+
+func NewFileRegistry(path string) (*FileRegistry, error) {
+	if path == "" {
+		return nil, errors.New("registry file path is empty or not provided")
+	}
+
+	r := &FileRegistry{
+		path:          path,
+		registrations: make(map[uint64]Registration, 1),
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return r, nil
+		}
+		return nil, fmt.Errorf("failed to read registry file: %w", err)
+	}
+
+	var registrations []Registration
+	err = json.Unmarshal(data, &registrations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse registry file %s: %w", path, err)
+	}
+	for _, registration := range registrations {
+		r.registrations[registration.Id] = registration
+	}
+	return r, nil
+}
+
+func (r *FileRegistry) Load() ([]Registration, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.list(), nil
+}
+
+func (r *FileRegistry) Save(registrations ...Registration) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, registration := range registrations {
+		r.registrations[registration.Id] = registration
+	}
+	return r.write()
+}
+
+func (r *FileRegistry) Delete(id uint64) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.registrations[id]; !ok {
+		return nil
+	}
+	delete(r.registrations, id)
+	return r.write()
+}
+
+func (r *FileRegistry) Close() {
+}
+
+func (r *FileRegistry) list() []Registration {
+	ids := make(utils.Ids, 0, len(r.registrations))
+	for id := range r.registrations {
+		ids = append(ids, id)
+	}
+	sort.Sort(ids)
+	registrations := make([]Registration, len(ids))
+	for i, id := range ids {
+		registrations[i] = r.registrations[id]
+	}
+	return registrations
+}
+
+func (r *FileRegistry) write() error {
+	data, err := json.MarshalIndent(r.list(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to the temporary file first to not leave the registry broken on crash
+	tmp, err := ioutil.TempFile(filepath.Dir(r.path), filepath.Base(r.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create registry file: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write registry file: %w", err)
+	}
+	return os.Rename(tmp.Name(), r.path)
+}
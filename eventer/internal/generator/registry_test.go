@@ -0,0 +1,151 @@
+package generator
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+	"github.com/sibedge-llc/dp-services/eventer/internal/event"
+)
+
+func TestFileRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registry.json")
+	r, err := NewFileRegistry(path)
+	if err != nil {
+		t.Fatal("create registry failed", err)
+	}
+
+	err = r.Save(
+		Registration{Id: 2, Event: event.EventDesc{Id: "e2", Count: 10}, Count: 4},
+		Registration{Id: 1, Event: event.EventDesc{Id: "e1", Schema: []byte("{}")}, Count: -1},
+	)
+	if err != nil {
+		t.Fatal("save registrations failed", err)
+	}
+	err = r.Delete(2)
+	if err != nil {
+		t.Fatal("delete registration failed", err)
+	}
+	err = r.Save(Registration{Id: 3, Destination: event.DestinationDesc{Id: "d1", Type: event.DestinationTypeKafka}, Count: 7})
+	if err != nil {
+		t.Fatal("save registration failed", err)
+	}
+
+	r, err = NewFileRegistry(path)
+	if err != nil {
+		t.Fatal("reopen registry failed", err)
+	}
+	registrations, err := r.Load()
+	if err != nil {
+		t.Fatal("load registrations failed", err)
+	}
+	if len(registrations) != 2 {
+		t.Fatalf("expected 2 registrations, got %d", len(registrations))
+	}
+	if registrations[0].Id != 1 || string(registrations[0].Event.Schema) != "{}" || registrations[0].Count != -1 {
+		t.Errorf("unexpected first registration: %+v", registrations[0])
+	}
+	if registrations[1].Id != 3 || registrations[1].Destination.Type != event.DestinationTypeKafka || registrations[1].Count != 7 {
+		t.Errorf("unexpected second registration: %+v", registrations[1])
+	}
+}
+
+func TestServiceRegistry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "registry.json")
+	r, err := NewFileRegistry(path)
+	if err != nil {
+		t.Fatal("create registry failed", err)
+	}
+	eventDesc := event.EventDesc{Id: "e1", Schema: []byte("{}"), Count: -1, Interval: "1h"}
+	pendingDesc := event.EventDesc{Id: "e2", Schema: []byte("{}"), Count: -1, Interval: "1h"}
+	pendingId, err := makeGeneratorId(pendingDesc, &testDestination{})
+	if err != nil {
+		t.Fatal("make generator id failed", err)
+	}
+	err = r.Save(
+		Registration{Id: 1, Event: eventDesc, Count: 5},
+		Registration{Id: pendingId, Event: pendingDesc, Count: 3, Redacted: true},
+	)
+	if err != nil {
+		t.Fatal("save registrations failed", err)
+	}
+
+	s, err := New(ctx, "instance", r, time.Hour, false)
+	if err != nil {
+		t.Fatal("create service failed", err)
+	}
+	registrations, err := s.LoadRegistrations()
+	if err != nil {
+		t.Fatal("load registrations failed", err)
+	}
+	if len(registrations) != 1 || registrations[0].Id != 1 {
+		t.Fatalf("expected only not redacted registration, got %+v", registrations)
+	}
+	if pending := s.GetPending(); len(pending) != 1 || pending[0].Id != pendingId {
+		t.Fatalf("expected redacted registration to be pending, got %+v", pending)
+	}
+
+	destinationDesc := event.DestinationDesc{
+		Id:       "d1",
+		Type:     event.DestinationTypePostgres,
+		Postgres: &config.PostgresConfig{Host: "db", Password: "secret"},
+	}
+	g, err := s.RestoreGenerator(Registration{Id: 1, Event: eventDesc, Destination: destinationDesc}, &testDestination{})
+	if err != nil {
+		t.Fatal("restore generator failed", err)
+	}
+	if count, _ := g.GetStatus(); count != 5 {
+		t.Errorf("expected restored count 5, got %d", count)
+	}
+	s.DropRestored()
+	if len(s.GetPending()) != 1 {
+		t.Fatal("pending registration is dropped along with not restored ones")
+	}
+	s.Sync()
+	saved, err := r.Load()
+	if err != nil {
+		t.Fatal("load registrations failed", err)
+	}
+	if len(saved) != 2 {
+		t.Fatalf("expected pending registration to be kept in registry, got %+v", saved)
+	}
+
+	// Pending generator continues once it's added again along with the credentials
+	pending, err := s.RegisterGenerator(pendingDesc, destinationDesc, &testDestination{})
+	if err != nil {
+		t.Fatal("register pending generator failed", err)
+	}
+	if count, _ := pending.GetStatus(); count != 3 || len(s.GetPending()) != 0 {
+		t.Errorf("expected pending count 3 to be restored, got %d", count)
+	}
+	err = s.UnregisterGenerator(pending.id)
+	if err != nil {
+		t.Fatal("unregister generator failed", err)
+	}
+	s.Close()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal("read registry failed", err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("registry contains the password: %s", data)
+	}
+	registrations, err = r.Load()
+	if err != nil {
+		t.Fatal("load registrations failed", err)
+	}
+	if len(registrations) != 1 || registrations[0].Id != g.id || !registrations[0].Redacted {
+		t.Errorf("expected only redacted registration of the restored generator, got %+v", registrations)
+	}
+	if destinationDesc.Postgres.Password != "secret" {
+		t.Error("destination desc is changed by redaction")
+	}
+}
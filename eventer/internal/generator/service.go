@@ -4,47 +4,177 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
+
+	"go.uber.org/zap"
 
 	"github.com/sibedge-llc/dp-services/eventer/internal/event"
+	"github.com/sibedge-llc/dp-services/eventer/internal/metrics"
 	"github.com/sibedge-llc/dp-services/eventer/internal/utils"
 )
 
 var (
 	ErrorNotFound = errors.New("not found")
+	ErrorStopped  = errors.New("stopped")
 )
 
+type Filter struct {
+	DestinationType string
+	Dataset         string
+	State           *State
+}
+
+func (f Filter) Match(generator *Generator) bool {
+	if f.DestinationType != "" && f.DestinationType != generator.GetDestination().GetType() {
+		return false
+	}
+	if f.Dataset != "" && f.Dataset != generator.GetDataset() {
+		return false
+	}
+	if f.State != nil && *f.State != generator.GetState() {
+		return false
+	}
+	return true
+}
+
 type Service struct {
-	ctx        context.Context
-	instanceId string
-	lock       sync.Mutex
-	generators map[uint64]*Generator
+	ctx           context.Context
+	instanceId    string
+	lock          sync.Mutex
+	generators    map[uint64]*Generator
+	registry      Registry
+	registrations map[uint64]Registration
+	restored      map[uint64]Registration
+	pending       map[uint64]Registration
+	changed       map[uint64]bool
+	registryLock  sync.Mutex
+	secrets       bool
 }
 
-func New(ctx context.Context, instanceId string) (*Service, error) {
+func New(ctx context.Context, instanceId string, registry Registry, syncInterval time.Duration, secrets bool) (*Service, error) {
 	s := &Service{
-		ctx:        ctx,
-		instanceId: instanceId,
-		generators: make(map[uint64]*Generator, 1),
+		ctx:           ctx,
+		instanceId:    instanceId,
+		generators:    make(map[uint64]*Generator, 1),
+		registry:      registry,
+		registrations: make(map[uint64]Registration, 1),
+		restored:      make(map[uint64]Registration, 1),
+		pending:       make(map[uint64]Registration, 1),
+		changed:       make(map[uint64]bool, 1),
+		secrets:       secrets,
+	}
+	if registry != nil {
+		if syncInterval <= 0 {
+			return nil, fmt.Errorf("registry sync interval must be positive")
+		}
+		go s.run(syncInterval)
 	}
 	return s, nil
 }
 
-func (s *Service) RegisterGenerator(eventDesc event.EventDesc, desination Destinaton) (*Generator, error) {
-	eventId, err := utils.ObjectToJsonId(eventDesc, false)
+func (s *Service) LoadRegistrations() ([]Registration, error) {
+	if s.registry == nil {
+		return nil, nil
+	}
+
+	registrations, err := s.registry.Load()
 	if err != nil {
-		return nil, fmt.Errorf("failed to make id for event desc: %w", err)
+		return nil, fmt.Errorf("failed to load registrations: %w", err)
 	}
 
-	generatorId, err := utils.ObjectToJsonId(map[string]interface{}{
-		"event_id":       eventId,
-		"destination_id": desination.GetId(),
-	}, false)
+	result := make([]Registration, 0, len(registrations))
+	restored := make(map[uint64]Registration, len(registrations))
+	pending := make(map[uint64]Registration)
+	for _, registration := range registrations {
+		// Infinite generators are saved with -1 count so zero means the generator is finished
+		if registration.Count == 0 {
+			err := s.registry.Delete(registration.Id)
+			if err != nil {
+				zap.L().Error("failed to delete finished registration", zap.Uint64("id", registration.Id), zap.Error(err))
+			}
+			continue
+		}
+		// The generator can't be restored without credentials, so the registration is kept until it's added again
+		if registration.Redacted {
+			zap.L().Warn("registration is saved without destination credentials, the generator is restored once it's added again along with them", zap.Uint64("id", registration.Id))
+			pending[registration.Id] = registration
+			continue
+		}
+		restored[registration.Id] = registration
+		result = append(result, registration)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for id, registration := range restored {
+		s.restored[id] = registration
+	}
+	for id, registration := range pending {
+		s.pending[id] = registration
+	}
+	return result, nil
+}
+
+// GetPending returns the registrations saved without destination credentials ordered by id
+func (s *Service) GetPending() []Registration {
+	s.lock.Lock()
+	ids := make(utils.Ids, 0, len(s.pending))
+	for id := range s.pending {
+		ids = append(ids, id)
+	}
+	sort.Sort(ids)
+	result := make([]Registration, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, s.pending[id])
+	}
+	s.lock.Unlock()
+	return result
+}
+
+func (s *Service) RestoreGenerator(registration Registration, desination Destinaton) (*Generator, error) {
+	generatorId, err := makeGeneratorId(registration.Event, desination)
+	if err != nil {
+		return nil, err
+	}
+
+	// The destination id depends on the default config, so the registration moves to the new id if it's changed
+	if generatorId != registration.Id {
+		s.lock.Lock()
+		restored, ok := s.restored[registration.Id]
+		if ok {
+			delete(s.restored, registration.Id)
+			if _, ok := s.restored[generatorId]; !ok {
+				s.restored[generatorId] = restored
+			}
+			s.changed[registration.Id] = true
+		}
+		s.lock.Unlock()
+	}
+	return s.RegisterGenerator(registration.Event, registration.Destination, desination)
+}
 
+// DropRestored deletes the loaded registrations whose generators were not restored
+func (s *Service) DropRestored() {
+	defer s.write()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for id := range s.restored {
+		zap.L().Warn("registration is dropped as its generator was not restored", zap.Uint64("id", id))
+		delete(s.restored, id)
+		s.changed[id] = true
+	}
+}
+
+func (s *Service) RegisterGenerator(eventDesc event.EventDesc, destinationDesc event.DestinationDesc, desination Destinaton) (*Generator, error) {
+	generatorId, err := makeGeneratorId(eventDesc, desination)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make id for generator: %w", err)
+		return nil, err
 	}
 
+	// Deferred before the unlock to write the registry after the lock is released
+	defer s.write()
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
@@ -53,26 +183,144 @@ func (s *Service) RegisterGenerator(eventDesc event.EventDesc, desination Destin
 		return generator, nil
 	}
 
+	registration := Registration{
+		Id:          generatorId,
+		Event:       eventDesc,
+		Destination: destinationDesc,
+	}
+	restored, isRestored := s.restored[generatorId]
+	if !isRestored {
+		// Generator saved without credentials continues once it's added along with them
+		restored, isRestored = s.pending[generatorId]
+	}
+	if isRestored {
+		delete(s.restored, generatorId)
+		delete(s.pending, generatorId)
+		restored.apply(&eventDesc)
+	}
+
 	generator, err = NewGenerator(s.ctx, s.instanceId, generatorId, eventDesc, desination)
 	if err != nil {
 		return nil, fmt.Errorf("make generator failed: %w", err)
 	}
 	s.generators[generatorId] = generator
 
+	if isRestored && restored.Paused {
+		generator.Pause()
+	}
+
+	if s.registry != nil {
+		if !s.secrets {
+			registration.redact()
+		}
+		s.registrations[generatorId] = registration
+		s.save(generator)
+	}
+
 	return generator, nil
 }
 
+func makeGeneratorId(eventDesc event.EventDesc, desination Destinaton) (uint64, error) {
+	eventId, err := utils.ObjectToJsonId(eventDesc, false)
+	if err != nil {
+		return 0, fmt.Errorf("failed to make id for event desc: %w", err)
+	}
+
+	generatorId, err := utils.ObjectToJsonId(map[string]interface{}{
+		"event_id":       eventId,
+		"destination_id": desination.GetId(),
+	}, false)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to make id for generator: %w", err)
+	}
+	return generatorId, nil
+}
+
+func (s *Service) GetInstanceId() string {
+	return s.instanceId
+}
+
 func (s *Service) UnregisterGenerator(generatorId uint64) error {
+	defer s.write()
 	s.lock.Lock()
-	defer s.lock.Unlock()
-
 	generator, ok := s.generators[generatorId]
 	if ok {
-		generator.Stop()
 		delete(s.generators, generatorId)
+		s.unregister(generatorId)
+	} else if _, ok := s.pending[generatorId]; ok {
+		delete(s.pending, generatorId)
+		s.changed[generatorId] = true
+		s.lock.Unlock()
 		return nil
 	}
-	return ErrorNotFound
+	s.lock.Unlock()
+	if !ok {
+		return ErrorNotFound
+	}
+
+	// Stop waits for the destination to flush, so it's done without the lock
+	generator.Stop()
+	metrics.DeleteGenerator(generator.GetId())
+	return nil
+}
+
+func (s *Service) PauseGenerator(generatorId uint64) (*Generator, error) {
+	return s.setGeneratorPaused(generatorId, true)
+}
+
+func (s *Service) ResumeGenerator(generatorId uint64) (*Generator, error) {
+	return s.setGeneratorPaused(generatorId, false)
+}
+
+func (s *Service) setGeneratorPaused(generatorId uint64, paused bool) (*Generator, error) {
+	defer s.write()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	generator, ok := s.generators[generatorId]
+	if !ok {
+		return nil, ErrorNotFound
+	}
+	if generator.IsStopped() {
+		return nil, ErrorStopped
+	}
+
+	var changed bool
+	if paused {
+		changed = generator.Pause()
+	} else {
+		changed = generator.Resume()
+	}
+
+	if changed {
+		s.save(generator)
+	}
+	return generator, nil
+}
+
+func (s *Service) UpdateGenerator(generatorId uint64, update Update) (*Generator, error) {
+	s.lock.Lock()
+	generator, ok := s.generators[generatorId]
+	s.lock.Unlock()
+	if !ok {
+		return nil, ErrorNotFound
+	}
+	if generator.IsStopped() {
+		return nil, ErrorStopped
+	}
+
+	// Update waits for the run loop, so it's done without the lock
+	err := generator.Update(update)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	s.save(generator)
+	s.lock.Unlock()
+	s.write()
+	return generator, nil
 }
 
 func (s *Service) GetGenerator(generatorId uint64) *Generator {
@@ -80,3 +328,132 @@ func (s *Service) GetGenerator(generatorId uint64) *Generator {
 	defer s.lock.Unlock()
 	return s.generators[generatorId]
 }
+
+func (s *Service) ListGenerators(filter Filter, cursor uint64, limit int) ([]*Generator, uint64) {
+	s.lock.Lock()
+	ids := make(utils.Ids, 0, len(s.generators))
+	for id := range s.generators {
+		if id > cursor {
+			ids = append(ids, id)
+		}
+	}
+	generators := make(map[uint64]*Generator, len(ids))
+	for _, id := range ids {
+		generators[id] = s.generators[id]
+	}
+	s.lock.Unlock()
+
+	sort.Sort(ids)
+
+	result := make([]*Generator, 0, limit)
+	for _, id := range ids {
+		generator := generators[id]
+		if !filter.Match(generator) {
+			continue
+		}
+		if limit > 0 && len(result) == limit {
+			return result, result[len(result)-1].id
+		}
+		result = append(result, generator)
+	}
+	return result, 0
+}
+
+func (s *Service) Sync() {
+	s.lock.Lock()
+	s.sync()
+	s.lock.Unlock()
+	s.write()
+}
+
+func (s *Service) Close() {
+	s.Sync()
+	s.registryLock.Lock()
+	defer s.registryLock.Unlock()
+	s.lock.Lock()
+	registry := s.registry
+	s.registry = nil
+	s.lock.Unlock()
+	if registry != nil {
+		registry.Close()
+	}
+}
+
+func (s *Service) run(syncInterval time.Duration) {
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.Sync()
+		}
+	}
+}
+
+func (s *Service) sync() {
+	for id := range s.registrations {
+		generator, ok := s.generators[id]
+		if !ok || generator.IsStopped() {
+			s.unregister(id)
+			continue
+		}
+		s.changed[id] = true
+	}
+}
+
+func (s *Service) save(generator *Generator) {
+	if _, ok := s.registrations[generator.id]; ok {
+		s.changed[generator.id] = true
+	}
+}
+
+func (s *Service) unregister(generatorId uint64) {
+	if _, ok := s.registrations[generatorId]; !ok {
+		return
+	}
+	delete(s.registrations, generatorId)
+	s.changed[generatorId] = true
+}
+
+// write saves the changed registrations, it's called without the service lock to not block it on registry I/O
+func (s *Service) write() {
+	s.registryLock.Lock()
+	defer s.registryLock.Unlock()
+
+	s.lock.Lock()
+	registry := s.registry
+	saved := make([]Registration, 0, len(s.changed))
+	deleted := make([]uint64, 0, len(s.changed))
+	for id := range s.changed {
+		registration, ok := s.registrations[id]
+		if !ok {
+			deleted = append(deleted, id)
+			continue
+		}
+		if generator, ok := s.generators[id]; ok {
+			registration.capture(generator)
+			s.registrations[id] = registration
+		}
+		saved = append(saved, registration)
+	}
+	s.changed = make(map[uint64]bool, 1)
+	s.lock.Unlock()
+
+	if registry == nil {
+		return
+	}
+	if len(saved) > 0 {
+		err := registry.Save(saved...)
+		if err != nil {
+			zap.L().Error("failed to save registrations", zap.Error(err))
+		}
+	}
+	for _, id := range deleted {
+		err := registry.Delete(id)
+		if err != nil {
+			zap.L().Error("failed to delete registration", zap.Uint64("id", id), zap.Error(err))
+		}
+	}
+}
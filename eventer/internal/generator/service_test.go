@@ -0,0 +1,133 @@
+package generator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/event"
+)
+
+type blockingDestination struct {
+	testDestination
+	release chan struct{}
+}
+
+func (d *blockingDestination) unblock() {
+	select {
+	case <-d.release:
+	default:
+		close(d.release)
+	}
+}
+
+func (d *blockingDestination) Send(evt *event.Event) error {
+	<-d.release
+	return d.testDestination.Send(evt)
+}
+
+func TestServiceUpdateUnlocked(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, err := New(ctx, "instance", nil, 0, false)
+	if err != nil {
+		t.Fatal("create service failed", err)
+	}
+	destination := &blockingDestination{release: make(chan struct{})}
+	g, err := s.RegisterGenerator(event.EventDesc{
+		Id:       "e1",
+		Schema:   []byte(`{id: 1}`),
+		Count:    -1,
+		Interval: "1ms",
+	}, event.DestinationDesc{}, destination)
+	if err != nil {
+		t.Fatal("register generator failed", err)
+	}
+	defer g.Stop()
+	defer destination.unblock()
+
+	// The run loop is blocked by the destination, so the update waits for it
+	time.Sleep(10 * time.Millisecond)
+	updated := make(chan error, 1)
+	count := int64(5)
+	go func() {
+		_, err := s.UpdateGenerator(g.id, Update{Count: &count})
+		updated <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	listed := make(chan struct{})
+	go func() {
+		s.ListGenerators(Filter{}, 0, 0)
+		close(listed)
+	}()
+	select {
+	case <-listed:
+	case <-time.After(time.Second):
+		t.Fatal("service is locked by the pending update")
+	}
+
+	destination.unblock()
+	select {
+	case err := <-updated:
+		if err != nil {
+			t.Error("update generator failed", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("update is not applied")
+	}
+}
+
+func TestServiceUnregisterUnlocked(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, err := New(ctx, "instance", nil, 0, false)
+	if err != nil {
+		t.Fatal("create service failed", err)
+	}
+	destination := &blockingDestination{release: make(chan struct{})}
+	g, err := s.RegisterGenerator(event.EventDesc{
+		Id:       "e1",
+		Schema:   []byte(`{id: 1}`),
+		Count:    -1,
+		Interval: "1ms",
+	}, event.DestinationDesc{}, destination)
+	if err != nil {
+		t.Fatal("register generator failed", err)
+	}
+	defer destination.unblock()
+
+	// The run loop is blocked by the destination, so the stop waits for it
+	time.Sleep(10 * time.Millisecond)
+	unregistered := make(chan error, 1)
+	go func() {
+		unregistered <- s.UnregisterGenerator(g.id)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	listed := make(chan struct{})
+	go func() {
+		s.ListGenerators(Filter{}, 0, 0)
+		close(listed)
+	}()
+	select {
+	case <-listed:
+	case <-time.After(time.Second):
+		t.Fatal("service is locked by the pending stop")
+	}
+
+	destination.unblock()
+	select {
+	case err := <-unregistered:
+		if err != nil {
+			t.Error("unregister generator failed", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("generator is not stopped")
+	}
+	if s.GetGenerator(g.id) != nil {
+		t.Error("unregistered generator is still listed")
+	}
+}
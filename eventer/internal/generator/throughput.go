@@ -0,0 +1,185 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/event"
+)
+
+const (
+	throughputTick         = 10 * time.Millisecond
+	defaultBatchSize       = 100
+	maxThroughputAllowance = time.Second
+)
+
+func (s *Generator) initThroughput(ctx context.Context, instanceId string, eventDesc event.EventDesc) (*event.Event, error) {
+	workers := eventDesc.Throughput.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	s.batchSize = eventDesc.Throughput.BatchSize
+	if s.batchSize <= 0 {
+		s.batchSize = defaultBatchSize
+	}
+
+	// Every worker has its own vm since the vm is not safe for concurrent use
+	for i := 1; i < workers; i++ {
+		composer, err := event.NewComposerByContent(eventDesc.Dataset, instanceId, fmt.Sprint(s.id), eventDesc.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create composed based on schema: %w", err)
+		}
+		composer.EnableMetrics(fmt.Sprint(s.id))
+		if eventDesc.Seed != nil {
+			composer.SetSeed(*eventDesc.Seed + int64(i))
+		}
+		s.composers = append(s.composers, composer)
+	}
+
+	evt, err := s.composers[0].Compose()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compose event: %w", err)
+	}
+
+	s.pool = event.NewPool(ctx, s.composers, workers*s.batchSize)
+	return evt, nil
+}
+
+func (s *Generator) runThroughput(ctx context.Context) {
+	ticker := time.NewTicker(throughputTick)
+	defer func() {
+		ticker.Stop()
+		s.destination.Flush()
+		if s.dedicated {
+			s.destination.Close()
+		}
+		atomic.StoreInt64(&s.count, -2)
+		close(s.stopped)
+	}()
+
+	last := time.Now()
+	var allowance float64
+	batch := make([]*event.Event, 0, s.batchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case apply := <-s.updates:
+			apply()
+		case now := <-ticker.C:
+			elapsed := now.Sub(last)
+			last = now
+			if s.gate.IsClosed() {
+				allowance = 0
+				continue
+			}
+
+			rate := s.GetTargetRate()
+			allowance += rate * elapsed.Seconds()
+			if limit := rate * maxThroughputAllowance.Seconds(); allowance > limit {
+				allowance = limit
+			}
+			n := int(allowance)
+			allowance -= float64(n)
+
+			for n > 0 {
+				size := n
+				if size > s.batchSize {
+					size = s.batchSize
+				}
+				var ok bool
+				batch, ok = s.collect(batch[:0], size)
+				if !ok {
+					zap.L().Info("Stop event.")
+					return
+				}
+				if len(batch) == 0 {
+					s.noEvents.Inc()
+				}
+				batch = batch[:s.reserve(len(batch))]
+				if len(batch) > 0 && !s.sendBatch(batch) {
+					return
+				}
+				if s.IsStopped() {
+					return
+				}
+				if len(batch) < size {
+					break
+				}
+				n -= len(batch)
+			}
+		}
+	}
+}
+
+func (s *Generator) collect(batch []*event.Event, size int) ([]*event.Event, bool) {
+	for len(batch) < size {
+		select {
+		case evt := <-s.pool.Events():
+			if evt.IsStop {
+				return batch, false
+			}
+			batch = append(batch, evt)
+		default:
+			// Events are not composed fast enough, the rest is skipped until the next tick
+			return batch, true
+		}
+	}
+	return batch, true
+}
+
+// sendBatch returns false if the generator has to stop
+func (s *Generator) sendBatch(batch []*event.Event) bool {
+	report := s.reporter()
+	for _, evt := range batch {
+		evt.Delivery = report
+	}
+	// Events of the buffered destination are reported one by one, so they are sent one by one as well
+	if destination, ok := s.destination.(BatchDestinaton); ok && !s.buffered {
+		err := destination.SendBatch(batch)
+		if err != nil {
+			zap.L().Error("send events batch failed.", zap.Error(err))
+			return !s.addFailed(len(batch), err)
+		}
+		s.addSent(len(batch))
+		return true
+	}
+	for _, evt := range batch {
+		err := s.destination.Send(evt)
+		if err != nil {
+			zap.L().Error("send event failed.", zap.Error(err))
+			if s.addFailed(1, err) {
+				return false
+			}
+			continue
+		}
+		if !s.buffered {
+			s.addSent(1)
+		}
+	}
+	return true
+}
+
+func (s *Generator) reserve(n int) int {
+	for {
+		count, isInfinite := s.GetStatus()
+		if isInfinite {
+			return n
+		}
+		if count <= 0 {
+			return 0
+		}
+		reserved := int64(n)
+		if reserved > count {
+			reserved = count
+		}
+		if atomic.CompareAndSwapInt64(&s.count, count, count-reserved) {
+			return int(reserved)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package kafka
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	c_kafka "github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/event"
+)
+
+const (
+	envelopeKey       = "key"
+	envelopeValue     = "value"
+	envelopeHeaders   = "headers"
+	envelopeTimestamp = "timestamp"
+	envelopePartition = "partition"
+)
+
+func isEnvelopeField(name string) bool {
+	switch name {
+	case envelopeKey, envelopeValue, envelopeHeaders, envelopeTimestamp, envelopePartition:
+		return true
+	}
+	return false
+}
+
+// isEnvelope tells whether the object is the envelope, plain events may have the fields named as the envelope ones,
+// so only the object with the value is the envelope, the null value included
+func isEnvelope(obj event.EventObject) bool {
+	_, ok := obj[envelopeValue]
+	return ok
+}
+
+// toMessage maps the event onto the message, the event is either the value or the envelope of the message
+func (p *Producer) toMessage(evt *event.Event) (*c_kafka.Message, error) {
+	msg := &c_kafka.Message{
+		TopicPartition: c_kafka.TopicPartition{Topic: &p.topic, Partition: c_kafka.PartitionAny},
+	}
+	if !p.cfg.Envelope {
+		msg.Key = p.getKey(evt)
+		return msg, p.setValue(msg, evt.Json)
+	}
+
+	// Object without the value is the value itself
+	obj := evt.Object
+	if !isEnvelope(obj) {
+		msg.Key = p.getKey(evt)
+		return msg, p.setValue(msg, evt.Json)
+	}
+	for name := range obj {
+		if !isEnvelopeField(name) {
+			return nil, fmt.Errorf("unknown envelope field %s", name)
+		}
+	}
+
+	// Null value is the tombstone
+	value := obj[envelopeValue]
+	if value != nil {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal message value: %w", err)
+		}
+		err = p.setValue(msg, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if key, ok := obj[envelopeKey]; ok {
+		if key != nil {
+			msg.Key = []byte(keyString(key))
+		}
+	} else if valueObj, ok := value.(map[string]interface{}); ok {
+		valueEvt := &event.Event{Id: event.NoEventId, Object: valueObj}
+		if _, ok := valueObj["id"]; ok {
+			valueEvt.Id = event.GetId(valueObj)
+		}
+		msg.Key = p.getKey(valueEvt)
+	}
+
+	if headers, ok := obj[envelopeHeaders]; ok && headers != nil {
+		headersObj, ok := headers.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("headers must be an object, got %T", headers)
+		}
+		names := make([]string, 0, len(headersObj))
+		for name := range headersObj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			header := c_kafka.Header{Key: name}
+			if v := headersObj[name]; v != nil {
+				header.Value = []byte(keyString(v))
+			}
+			msg.Headers = append(msg.Headers, header)
+		}
+	}
+
+	if timestamp, ok := obj[envelopeTimestamp]; ok && timestamp != nil {
+		t, err := toTimestamp(timestamp)
+		if err != nil {
+			return nil, err
+		}
+		msg.Timestamp = t
+		msg.TimestampType = c_kafka.TimestampCreateTime
+	}
+
+	if partition, ok := obj[envelopePartition]; ok && partition != nil {
+		v, ok := partition.(float64)
+		if !ok || v < 0 || v != math.Trunc(v) || v > math.MaxInt32 {
+			return nil, fmt.Errorf("partition must be non negative integer, got %v", partition)
+		}
+		msg.TopicPartition.Partition = int32(v)
+	}
+	return msg, nil
+}
+
+// setValue encodes json value to the configured format
+func (p *Producer) setValue(msg *c_kafka.Message, data []byte) error {
+	if p.serializer == nil {
+		msg.Value = data
+		return nil
+	}
+	value, err := p.serializer.serialize(data)
+	if err != nil {
+		return err
+	}
+	msg.Value = value
+	return nil
+}
+
+// toTimestamp treats numbers as unix time in seconds and strings as RFC 3339 time
+func toTimestamp(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case float64:
+		sec, frac := math.Modf(t)
+		return time.Unix(int64(sec), int64(frac*float64(time.Second))), nil
+	case string:
+		timestamp, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			return timestamp, fmt.Errorf("failed to parse timestamp %s: %w", t, err)
+		}
+		return timestamp, nil
+	}
+	return time.Time{}, errors.New("timestamp must be unix time or RFC 3339 string")
+}
@@ -0,0 +1,50 @@
+package kafka
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/event"
+)
+
+const defaultKeySeparator = ":"
+
+// getKey makes the message key of the event fields, the event id is the key unless fields are configured
+func (p *Producer) getKey(evt *event.Event) []byte {
+	key := p.cfg.Key
+	if key == nil {
+		return evt.Id
+	}
+	if len(key.Fields) == 0 {
+		return nil
+	}
+	separator := key.Separator
+	if separator == "" {
+		separator = defaultKeySeparator
+	}
+	parts := make([]string, len(key.Fields))
+	for i, field := range key.Fields {
+		v, _ := event.GetPath(evt.Object, field)
+		parts[i] = keyString(v)
+	}
+	return []byte(strings.Join(parts, separator))
+}
+
+func keyString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
 	c_kafka "github.com/confluentinc/confluent-kafka-go/kafka"
@@ -11,6 +13,12 @@ import (
 
 	"github.com/sibedge-llc/dp-services/eventer/internal/config"
 	"github.com/sibedge-llc/dp-services/eventer/internal/event"
+	"github.com/sibedge-llc/dp-services/eventer/internal/metrics"
+)
+
+const (
+	queueFullTimeoutMs = 100
+	queueFullTimeout   = 5 * time.Second
 )
 
 type Producer struct {
@@ -18,7 +26,11 @@ type Producer struct {
 	producer *c_kafka.Producer
 	topic    string
 	id       uint64
-	servers  string
+	cfg      *config.KafkaConfig
+
+	lock       sync.Mutex
+	serializer *serializer
+	txn        *transaction
 }
 
 func NewProducer(ctx context.Context, id uint64, cfg *config.KafkaConfig) (*Producer, error) {
@@ -27,9 +39,7 @@ func NewProducer(ctx context.Context, id uint64, cfg *config.KafkaConfig) (*Prod
 		return nil, errors.New("topic name is empty or not provided")
 	}
 
-	p, err := c_kafka.NewProducer(&c_kafka.ConfigMap{
-		"bootstrap.servers": cfg.BootstrapServers,
-	})
+	p, err := c_kafka.NewProducer(toConfigMap(cfg))
 	if err != nil {
 		return nil, err
 	}
@@ -40,13 +50,27 @@ func NewProducer(ctx context.Context, id uint64, cfg *config.KafkaConfig) (*Prod
 			case <-ctx.Done():
 				zap.L().Info("stop listen send events.", zap.String("topic", cfg.Topic))
 				return
-			case e := <-p.Events():
+			case e, ok := <-p.Events():
+				if !ok {
+					return
+				}
 				switch ev := e.(type) {
 				case *c_kafka.Message:
+					// Reports are passed back to the generator sent the message as the producer is shared
+					report, _ := ev.Opaque.(event.DeliveryReporter)
 					if ev.TopicPartition.Error != nil {
 						zap.L().Error("Failed to deliver message", zap.Stringer("partition", ev.TopicPartition))
+						var generatorId string
+						if report != nil {
+							generatorId = report.GetGeneratorId()
+							report.Failed(ev.TopicPartition.Error)
+						}
+						metrics.KafkaDeliveryFailures.WithLabelValues(generatorId, fmt.Sprint(id), cfg.Topic).Inc()
 					} else {
-						zap.L().Info("Successfully produced record", zap.Stringer("partition", ev.TopicPartition))
+						zap.L().Debug("Successfully produced record", zap.Stringer("partition", ev.TopicPartition))
+						if report != nil {
+							report.Delivered()
+						}
 					}
 				}
 			}
@@ -58,27 +82,145 @@ func NewProducer(ctx context.Context, id uint64, cfg *config.KafkaConfig) (*Prod
 		producer: p,
 		topic:    cfg.Topic,
 		id:       id,
-		servers:  cfg.BootstrapServers,
+		cfg:      cfg,
 	}, nil
 }
 
+// toConfigMap makes librdkafka properties, properties given explicitly take precedence over the settings
+func toConfigMap(cfg *config.KafkaConfig) *c_kafka.ConfigMap {
+	m := c_kafka.ConfigMap{
+		"bootstrap.servers": cfg.BootstrapServers,
+	}
+	if cfg.ClientId != "" {
+		m["client.id"] = cfg.ClientId
+	}
+	if cfg.Partitioner != "" {
+		m["partitioner"] = cfg.Partitioner
+	}
+	if cfg.SecurityProtocol != "" {
+		m["security.protocol"] = cfg.SecurityProtocol
+	}
+	if cfg.Sasl != nil {
+		if cfg.Sasl.Mechanism != "" {
+			m["sasl.mechanisms"] = cfg.Sasl.Mechanism
+		}
+		if cfg.Sasl.Username != "" {
+			m["sasl.username"] = cfg.Sasl.Username
+		}
+		if cfg.Sasl.Password != "" {
+			m["sasl.password"] = cfg.Sasl.Password
+		}
+	}
+	if cfg.Ssl != nil {
+		if cfg.Ssl.CaLocation != "" {
+			m["ssl.ca.location"] = cfg.Ssl.CaLocation
+		}
+		if cfg.Ssl.CertificateLocation != "" {
+			m["ssl.certificate.location"] = cfg.Ssl.CertificateLocation
+		}
+		if cfg.Ssl.KeyLocation != "" {
+			m["ssl.key.location"] = cfg.Ssl.KeyLocation
+		}
+		if cfg.Ssl.KeyPassword != "" {
+			m["ssl.key.password"] = cfg.Ssl.KeyPassword
+		}
+	}
+	for key, value := range cfg.Properties {
+		// Values are passed as strings as json numbers are decoded to float64 which librdkafka doesn't accept
+		switch v := value.(type) {
+		case float64:
+			m[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			m[key] = fmt.Sprint(v)
+		}
+	}
+	return &m
+}
+
 func (p *Producer) Init(evt *event.Event) error {
-	return p.ensureTopic(p.ctx, p.topic)
+	err := p.initSerializer()
+	if err != nil {
+		return err
+	}
+	_, err = p.toMessage(evt)
+	if err != nil {
+		return fmt.Errorf("invalid message: %w", err)
+	}
+	err = p.ensureTopic(p.ctx, p.topic)
+	if err != nil || p.txn == nil {
+		return err
+	}
+	return p.initTransactions()
+}
+
+// initSerializer registers the schema once, the producer is shared by the generators with the same config
+func (p *Producer) initSerializer() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.serializer != nil {
+		return nil
+	}
+	s, err := newSerializer(p.ctx, p.cfg)
+	if err != nil {
+		return err
+	}
+	p.serializer = s
+	return nil
 }
 
 func (p *Producer) GetId() uint64 {
 	return p.id
 }
 
+func (p *Producer) GetType() string {
+	return event.DestinationTypeKafka
+}
+
+func (p *Producer) GetTarget() string {
+	return p.topic
+}
+
 func (p *Producer) Send(evt *event.Event) error {
-	return p.producer.Produce(&c_kafka.Message{
-		TopicPartition: c_kafka.TopicPartition{Topic: &p.topic, Partition: c_kafka.PartitionAny},
-		Key:            evt.Id,
-		Value:          evt.Json,
-	}, nil)
+	msg, err := p.toMessage(evt)
+	if err != nil {
+		return err
+	}
+	if evt.Delivery != nil {
+		msg.Opaque = evt.Delivery
+	}
+	if p.txn != nil {
+		return p.produceInTransaction(msg)
+	}
+	return p.producer.Produce(msg, nil)
+}
+
+func (p *Producer) SendBatch(evts []*event.Event) error {
+	for _, evt := range evts {
+		err := p.Send(evt)
+		// Local queue is full, wait until some messages are delivered and retry, the queue stays full
+		// while the broker is unavailable, so the error is returned to not block the generator
+		deadline := time.Now().Add(queueFullTimeout)
+		for isQueueFull(err) && p.ctx.Err() == nil && time.Now().Before(deadline) {
+			p.producer.Flush(queueFullTimeoutMs)
+			err = p.Send(evt)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isQueueFull(err error) bool {
+	var kafkaErr c_kafka.Error
+	return errors.As(err, &kafkaErr) && kafkaErr.Code() == c_kafka.ErrQueueFull
 }
 
 func (p *Producer) Flush() {
+	if p.txn != nil {
+		p.flushTransaction()
+	}
 	p.producer.Flush(int(time.Second.Microseconds()))
 }
 
@@ -96,12 +238,37 @@ func (p *Producer) ensureTopic(ctx context.Context, topic string) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	timeout := time.Second * 60
+
+	topicConfig := p.cfg.TopicConfig
+	if topicConfig == nil {
+		topicConfig = &config.KafkaTopicConfig{}
+	}
+	if topicConfig.MustExist {
+		metadata, err := a.GetMetadata(&topic, false, int(timeout.Milliseconds()))
+		if err != nil {
+			return fmt.Errorf("failed to get metadata of topic %s: %w", topic, err)
+		}
+		if t, ok := metadata.Topics[topic]; !ok || t.Error.Code() != c_kafka.ErrNoError {
+			return fmt.Errorf("topic %s doesn't exist", topic)
+		}
+		return nil
+	}
+
+	specification := c_kafka.TopicSpecification{
+		Topic:             topic,
+		NumPartitions:     topicConfig.Partitions,
+		ReplicationFactor: topicConfig.ReplicationFactor,
+		Config:            topicConfig.Configs,
+	}
+	if specification.NumPartitions <= 0 {
+		specification.NumPartitions = 1
+	}
+	if specification.ReplicationFactor <= 0 {
+		specification.ReplicationFactor = 1
+	}
 	results, err := a.CreateTopics(
 		ctx,
-		[]c_kafka.TopicSpecification{{
-			Topic:             topic,
-			NumPartitions:     1,
-			ReplicationFactor: 1}},
+		[]c_kafka.TopicSpecification{specification},
 		c_kafka.SetAdminOperationTimeout(timeout))
 	if err != nil {
 		return fmt.Errorf("Admin Client request error: %v\n", err)
@@ -115,6 +282,6 @@ func (p *Producer) ensureTopic(ctx context.Context, topic string) error {
 	return nil
 }
 
-func (p *Producer) GetServers() string {
-	return p.servers
+func (p *Producer) GetConfig() *config.KafkaConfig {
+	return p.cfg
 }
@@ -0,0 +1,174 @@
+package kafka
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	c_kafka "github.com/confluentinc/confluent-kafka-go/kafka"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+	"github.com/sibedge-llc/dp-services/eventer/internal/event"
+)
+
+func TestToConfigMap(t *testing.T) {
+	m := toConfigMap(&config.KafkaConfig{
+		BootstrapServers: "broker:9093",
+		ClientId:         "eventer",
+		SecurityProtocol: "sasl_ssl",
+		Sasl:             &config.KafkaSaslConfig{Mechanism: "SCRAM-SHA-512", Username: "user", Password: "secret"},
+		Ssl:              &config.KafkaSslConfig{CaLocation: "/certs/ca.pem"},
+		Properties: map[string]interface{}{
+			"linger.ms":          float64(5),
+			"enable.idempotence": true,
+			"client.id":          "override",
+		},
+	})
+	expected := &c_kafka.ConfigMap{
+		"bootstrap.servers":  "broker:9093",
+		"client.id":          "override",
+		"security.protocol":  "sasl_ssl",
+		"sasl.mechanisms":    "SCRAM-SHA-512",
+		"sasl.username":      "user",
+		"sasl.password":      "secret",
+		"ssl.ca.location":    "/certs/ca.pem",
+		"linger.ms":          "5",
+		"enable.idempotence": "true",
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("expected %v, got %v", expected, m)
+	}
+}
+
+func TestProducerKey(t *testing.T) {
+	evt := &event.Event{
+		Id: event.EventId("42"),
+		Object: event.EventObject{
+			"id":   float64(42),
+			"user": map[string]interface{}{"id": float64(1000000), "country": "jp"},
+		},
+	}
+	tests := []struct {
+		key      *config.KafkaKeyConfig
+		expected []byte
+	}{
+		{nil, []byte("42")},
+		{&config.KafkaKeyConfig{}, nil},
+		{&config.KafkaKeyConfig{Fields: []string{"user.id"}}, []byte("1000000")},
+		{&config.KafkaKeyConfig{Fields: []string{"user.country", "id", "missed"}, Separator: "|"}, []byte("jp|42|")},
+	}
+	for _, test := range tests {
+		p := &Producer{cfg: &config.KafkaConfig{Key: test.key}}
+		if key := p.getKey(evt); !reflect.DeepEqual(key, test.expected) {
+			t.Errorf("expected key %q for %+v, got %q", test.expected, test.key, key)
+		}
+	}
+}
+
+func TestProducerEnvelope(t *testing.T) {
+	p := &Producer{topic: "boo", cfg: &config.KafkaConfig{Envelope: true, Key: &config.KafkaKeyConfig{Fields: []string{"user"}}}}
+	msg, err := p.toMessage(&event.Event{Object: event.EventObject{
+		"value":     map[string]interface{}{"id": float64(1), "user": "bob"},
+		"headers":   map[string]interface{}{"source": "eventer", "version": float64(2)},
+		"timestamp": float64(1640995200.5),
+		"partition": float64(3),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Key) != "bob" || string(msg.Value) != `{"id":1,"user":"bob"}` || msg.TopicPartition.Partition != 3 {
+		t.Errorf("unexpected message %v", msg)
+	}
+	headers := []c_kafka.Header{{Key: "source", Value: []byte("eventer")}, {Key: "version", Value: []byte("2")}}
+	if !reflect.DeepEqual(msg.Headers, headers) {
+		t.Errorf("expected headers %v, got %v", headers, msg.Headers)
+	}
+	if !msg.Timestamp.Equal(time.Unix(1640995200, 5e8)) {
+		t.Errorf("unexpected timestamp %v", msg.Timestamp)
+	}
+
+	msg, err = p.toMessage(&event.Event{Object: event.EventObject{"key": "bob", "value": nil, "timestamp": "2022-01-01T00:00:00Z"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Key) != "bob" || msg.Value != nil || msg.TopicPartition.Partition != c_kafka.PartitionAny || !msg.Timestamp.Equal(time.Unix(1640995200, 0)) {
+		t.Errorf("unexpected tombstone %v", msg)
+	}
+
+	// Plain event may have fields named as the envelope ones
+	msg, err = p.toMessage(&event.Event{
+		Json:   []byte(`{"id":2,"key":"k","timestamp":"yesterday","user":"alice"}`),
+		Object: event.EventObject{"id": float64(2), "key": "k", "timestamp": "yesterday", "user": "alice"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Key) != "alice" || string(msg.Value) != `{"id":2,"key":"k","timestamp":"yesterday","user":"alice"}` || !msg.Timestamp.IsZero() {
+		t.Errorf("unexpected message of plain object %v", msg)
+	}
+
+	for _, obj := range []event.EventObject{
+		{"value": "x", "extra": 1},
+		{"value": "x", "partition": float64(-1)},
+		{"value": "x", "headers": "x"},
+		{"value": "x", "timestamp": "yesterday"},
+	} {
+		if _, err := p.toMessage(&event.Event{Object: obj}); err == nil {
+			t.Errorf("expected error for %v", obj)
+		}
+	}
+}
+
+func TestProducerForGenerator(t *testing.T) {
+	p := &Producer{ctx: context.Background(), topic: "boo", cfg: &config.KafkaConfig{BootstrapServers: "localhost:9092", Topic: "boo"}}
+	if d, err := p.ForGenerator(42); d != nil || err != nil {
+		t.Fatalf("expected shared producer, got %v, %v", d, err)
+	}
+
+	p.cfg.Properties = map[string]interface{}{"linger.ms": float64(5)}
+	p.cfg.Transaction = &config.KafkaTransactionConfig{Duration: "1s", AbortRatio: 0.1}
+	d, err := p.ForGenerator(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dedicated := d.(*Producer)
+	defer dedicated.Close()
+	if id := dedicated.cfg.Properties["transactional.id"]; id != "eventer-42" {
+		t.Errorf("unexpected transactional id %v", id)
+	}
+	if _, ok := p.cfg.Properties["transactional.id"]; ok {
+		t.Error("shared producer config is changed")
+	}
+	if txn := dedicated.txn; txn.messages != 0 || txn.duration != time.Second || txn.abortRatio != 0.1 {
+		t.Errorf("unexpected transaction %+v", txn)
+	}
+
+	p.cfg.Transaction = &config.KafkaTransactionConfig{AbortRatio: 2}
+	if _, err := p.ForGenerator(42); err == nil {
+		t.Error("expected error for abort ratio out of range")
+	}
+}
+
+func TestProducerQueueFull(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p, err := NewProducer(ctx, 1, &config.KafkaConfig{
+		BootstrapServers: "localhost:9",
+		Topic:            "boo",
+		Properties:       map[string]interface{}{"queue.buffering.max.messages": float64(1)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	evts := []*event.Event{
+		{Id: event.EventId("1"), Json: event.EventJson(`{"id":1}`)},
+		{Id: event.EventId("2"), Json: event.EventJson(`{"id":2}`)},
+	}
+	err = p.SendBatch(evts)
+	if !isQueueFull(err) {
+		t.Errorf("expected queue full error, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+)
+
+const (
+	schemaTypeAvro     = "AVRO"
+	schemaTypeProtobuf = "PROTOBUF"
+
+	schemaRegistryContentType = "application/vnd.schemaregistry.v1+json"
+	schemaRegistryTimeout     = 30 * time.Second
+)
+
+type registrySchema struct {
+	Id         int    `json:"id,omitempty"`
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+}
+
+// schemaRegistry is the client of Confluent Schema Registry REST API
+type schemaRegistry struct {
+	url  string
+	cfg  *config.KafkaSchemaRegistryConfig
+	http *http.Client
+}
+
+func newSchemaRegistry(cfg *config.KafkaSchemaRegistryConfig) *schemaRegistry {
+	return &schemaRegistry{
+		url:  strings.TrimRight(cfg.Url, "/"),
+		cfg:  cfg,
+		http: &http.Client{Timeout: schemaRegistryTimeout},
+	}
+}
+
+// register registers the schema under the subject, id of the already registered schema is returned as is
+func (r *schemaRegistry) register(ctx context.Context, subject string, schema *registrySchema) (int, error) {
+	in := *schema
+	// Avro is the default type, the registry doesn't accept it explicitly in old versions
+	if in.SchemaType == schemaTypeAvro {
+		in.SchemaType = ""
+	}
+	in.Id = 0
+	data, err := json.Marshal(in)
+	if err != nil {
+		return 0, err
+	}
+	var out registrySchema
+	err = r.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject)+"/versions", data, &out)
+	if err != nil {
+		return 0, err
+	}
+	return out.Id, nil
+}
+
+// latest fetches the latest version of the schema registered under the subject
+func (r *schemaRegistry) latest(ctx context.Context, subject string) (*registrySchema, error) {
+	var out registrySchema
+	err := r.do(ctx, http.MethodGet, "/subjects/"+url.PathEscape(subject)+"/versions/latest", nil, &out)
+	if err != nil {
+		return nil, err
+	}
+	if out.SchemaType == "" {
+		out.SchemaType = schemaTypeAvro
+	}
+	return &out, nil
+}
+
+func (r *schemaRegistry) do(ctx context.Context, method string, path string, in []byte, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		body = bytes.NewReader(in)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.url+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", schemaRegistryContentType)
+	if in != nil {
+		req.Header.Set("Content-Type", schemaRegistryContentType)
+	}
+	if r.cfg.Username != "" {
+		req.SetBasicAuth(r.cfg.Username, r.cfg.Password)
+	}
+
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("schema registry request %s failed: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read schema registry response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		errResponse := struct {
+			Message string `json:"message"`
+		}{}
+		if json.Unmarshal(data, &errResponse) != nil || errResponse.Message == "" {
+			errResponse.Message = http.StatusText(resp.StatusCode)
+		}
+		return fmt.Errorf("schema registry request %s failed with status %d: %s", path, resp.StatusCode, errResponse.Message)
+	}
+
+	err = json.Unmarshal(data, out)
+	if err != nil {
+		return fmt.Errorf("failed to parse schema registry response: %w", err)
+	}
+	return nil
+}
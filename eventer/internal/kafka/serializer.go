@@ -0,0 +1,203 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/linkedin/goavro/v2"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+)
+
+const (
+	wireMagicByte = 0
+	protoFileName = "schema.proto"
+)
+
+type encoder interface {
+	encode(data []byte) ([]byte, error)
+}
+
+// serializer encodes json values to the wire format of the schema registry: magic byte, schema id,
+// message indexes for protobuf and the encoded value
+type serializer struct {
+	id      int
+	indexes []int
+	encoder encoder
+}
+
+// newSerializer makes the serializer of the configured format, json values are sent as is, so there is no serializer.
+// Supplied schema is registered under the subject, otherwise the latest version of the subject is used
+func newSerializer(ctx context.Context, cfg *config.KafkaConfig) (*serializer, error) {
+	var schemaType string
+	switch cfg.Format {
+	case "", config.KafkaFormatJson:
+		return nil, nil
+	case config.KafkaFormatAvro:
+		schemaType = schemaTypeAvro
+	case config.KafkaFormatProtobuf:
+		schemaType = schemaTypeProtobuf
+	default:
+		return nil, fmt.Errorf("unknown message format %s", cfg.Format)
+	}
+	if cfg.SchemaRegistry == nil || cfg.SchemaRegistry.Url == "" {
+		return nil, fmt.Errorf("schema registry is required for %s format", cfg.Format)
+	}
+	registry := newSchemaRegistry(cfg.SchemaRegistry)
+	subject := cfg.Subject
+	if subject == "" {
+		subject = cfg.Topic + "-value"
+	}
+
+	schema := &registrySchema{Schema: cfg.Schema, SchemaType: schemaType}
+	if cfg.Schema == "" {
+		var err error
+		schema, err = registry.latest(ctx, subject)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get schema of subject %s: %w", subject, err)
+		}
+		if schema.SchemaType != schemaType {
+			return nil, fmt.Errorf("subject %s has %s schema, expected %s", subject, schema.SchemaType, schemaType)
+		}
+	}
+
+	s := &serializer{id: schema.Id}
+	if schemaType == schemaTypeAvro {
+		codec, err := goavro.NewCodecForStandardJSONFull(schema.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("invalid avro schema: %w", err)
+		}
+		s.encoder = &avroEncoder{codec: codec}
+	} else {
+		md, err := parseProtoMessage(schema.Schema, cfg.ProtoMessage)
+		if err != nil {
+			return nil, fmt.Errorf("invalid protobuf schema: %w", err)
+		}
+		s.encoder = &protobufEncoder{descriptor: md}
+		s.indexes = messageIndexes(md)
+	}
+
+	if cfg.Schema != "" {
+		id, err := registry.register(ctx, subject, schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to register schema of subject %s: %w", subject, err)
+		}
+		s.id = id
+	}
+	return s, nil
+}
+
+func (s *serializer) serialize(data []byte) ([]byte, error) {
+	payload, err := s.encoder.encode(data)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 5, 5+len(payload)+binary.MaxVarintLen64*(len(s.indexes)+1))
+	buf[0] = wireMagicByte
+	binary.BigEndian.PutUint32(buf[1:5], uint32(s.id))
+	if s.indexes != nil {
+		// Indexes of the first message are shortened to the single zero
+		if len(s.indexes) == 1 && s.indexes[0] == 0 {
+			buf = append(buf, 0)
+		} else {
+			buf = appendVarint(buf, int64(len(s.indexes)))
+			for _, index := range s.indexes {
+				buf = appendVarint(buf, int64(index))
+			}
+		}
+	}
+	return append(buf, payload...), nil
+}
+
+func appendVarint(buf []byte, v int64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(tmp[:], v)
+	return append(buf, tmp[:n]...)
+}
+
+type avroEncoder struct {
+	codec *goavro.Codec
+}
+
+func (e *avroEncoder) encode(data []byte) ([]byte, error) {
+	native, _, err := e.codec.NativeFromTextual(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert value to avro: %w", err)
+	}
+	return e.codec.BinaryFromNative(nil, native)
+}
+
+type protobufEncoder struct {
+	descriptor protoreflect.MessageDescriptor
+}
+
+func (e *protobufEncoder) encode(data []byte) ([]byte, error) {
+	msg := dynamicpb.NewMessage(e.descriptor)
+	err := protojson.Unmarshal(data, msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert value to protobuf: %w", err)
+	}
+	return proto.Marshal(msg)
+}
+
+// parseProtoMessage finds the message by the name with or without package, the first message is used by default
+func parseProtoMessage(schema string, name string) (protoreflect.MessageDescriptor, error) {
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{protoFileName: schema}),
+	}
+	fds, err := parser.ParseFiles(protoFileName)
+	if err != nil {
+		return nil, err
+	}
+	fd := fds[0]
+
+	var md *desc.MessageDescriptor
+	if name == "" {
+		if len(fd.GetMessageTypes()) == 0 {
+			return nil, errors.New("schema has no messages")
+		}
+		md = fd.GetMessageTypes()[0]
+	} else {
+		md = fd.FindMessage(name)
+		if md == nil && fd.GetPackage() != "" {
+			md = fd.FindMessage(fd.GetPackage() + "." + name)
+		}
+		if md == nil {
+			return nil, fmt.Errorf("message %s not found", name)
+		}
+	}
+
+	files, err := protodesc.NewFiles(desc.ToFileDescriptorSet(fd))
+	if err != nil {
+		return nil, err
+	}
+	d, err := files.FindDescriptorByName(protoreflect.FullName(md.GetFullyQualifiedName()))
+	if err != nil {
+		return nil, err
+	}
+	return d.(protoreflect.MessageDescriptor), nil
+}
+
+// messageIndexes returns the path of the message in the file, e.g. [1, 0] is the first nested message of the second message
+func messageIndexes(md protoreflect.MessageDescriptor) []int {
+	var indexes []int
+	var d protoreflect.Descriptor = md
+	for {
+		m, ok := d.(protoreflect.MessageDescriptor)
+		if !ok {
+			break
+		}
+		indexes = append([]int{m.Index()}, indexes...)
+		d = m.Parent()
+	}
+	return indexes
+}
@@ -0,0 +1,166 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+)
+
+const testAvroSchema = `{
+	"type": "record",
+	"name": "Click",
+	"fields": [
+		{"name": "id", "type": "long"},
+		{"name": "country", "type": ["null", "string"], "default": null}
+	]
+}`
+
+const testProtoSchema = `syntax = "proto3";
+package clicks;
+
+message Page {
+	int64 id = 1;
+}
+
+message Click {
+	message Position {
+		double x = 1;
+		double y = 2;
+	}
+	int64 id = 1;
+	string country = 2;
+	Position position = 3;
+}`
+
+// stubRegistry stores the latest schemas by subject
+func stubRegistry(t *testing.T, schemas map[string]*registrySchema) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/subjects/")
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(path, "/versions"):
+			var schema registrySchema
+			if err := json.NewDecoder(r.Body).Decode(&schema); err != nil {
+				t.Errorf("failed to decode schema: %v", err)
+			}
+			schema.Id = len(schemas) + 1
+			schemas[strings.TrimSuffix(path, "/versions")] = &schema
+			_ = json.NewEncoder(w).Encode(registrySchema{Id: schema.Id})
+		case r.Method == http.MethodGet && strings.HasSuffix(path, "/versions/latest"):
+			schema, ok := schemas[strings.TrimSuffix(path, "/versions/latest")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"error_code":40401,"message":"Subject not found."}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(schema)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+}
+
+func TestAvroSerializer(t *testing.T) {
+	schemas := map[string]*registrySchema{}
+	registry := stubRegistry(t, schemas)
+	defer registry.Close()
+
+	s, err := newSerializer(context.Background(), &config.KafkaConfig{
+		Topic:          "clicks",
+		Format:         config.KafkaFormatAvro,
+		Schema:         testAvroSchema,
+		SchemaRegistry: &config.KafkaSchemaRegistryConfig{Url: registry.URL},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema, ok := schemas["clicks-value"]; !ok || schema.Schema != testAvroSchema || schema.SchemaType != "" {
+		t.Fatalf("schema is not registered: %v", schemas)
+	}
+
+	data, err := s.serialize([]byte(`{"id":42,"country":"jp"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data[:5], []byte{0, 0, 0, 0, 1}) {
+		t.Errorf("unexpected header %v", data[:5])
+	}
+	codec, _ := goavro.NewCodec(testAvroSchema)
+	native, _, err := codec.NativeFromBinary(data[5:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := native.(map[string]interface{})
+	if record["id"] != int64(42) || record["country"].(map[string]interface{})["string"] != "jp" {
+		t.Errorf("unexpected record %v", record)
+	}
+
+	if _, err := s.serialize([]byte(`{"country":"jp"}`)); err == nil {
+		t.Error("expected error for missed field")
+	}
+}
+
+func TestProtobufSerializer(t *testing.T) {
+	schemas := map[string]*registrySchema{
+		"clicks": {Id: 7, Schema: testProtoSchema, SchemaType: schemaTypeProtobuf},
+	}
+	registry := stubRegistry(t, schemas)
+	defer registry.Close()
+
+	cfg := &config.KafkaConfig{
+		Topic:          "clicks",
+		Format:         config.KafkaFormatProtobuf,
+		Subject:        "clicks",
+		ProtoMessage:   "Click.Position",
+		SchemaRegistry: &config.KafkaSchemaRegistryConfig{Url: registry.URL},
+	}
+	s, err := newSerializer(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := s.serialize([]byte(`{"x":1.5,"y":2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Schema id 7 and indexes [1, 0] as zigzag varints: count 2, 1, 0
+	if !bytes.Equal(data[:8], []byte{0, 0, 0, 0, 7, 4, 2, 0}) {
+		t.Errorf("unexpected header %v", data[:8])
+	}
+	msg := dynamicpb.NewMessage(s.encoder.(*protobufEncoder).descriptor)
+	if err := proto.Unmarshal(data[8:], msg); err != nil {
+		t.Fatal(err)
+	}
+	if x := msg.Get(msg.Descriptor().Fields().ByName("x")).Float(); x != 1.5 {
+		t.Errorf("unexpected x %v", x)
+	}
+
+	cfg.ProtoMessage = ""
+	s, err = newSerializer(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = s.serialize([]byte(`{"id":"42"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data[:6], []byte{0, 0, 0, 0, 7, 0}) {
+		t.Errorf("unexpected header %v", data[:6])
+	}
+	if _, err := s.serialize([]byte(`{"id":1,"unknown":true}`)); err == nil {
+		t.Error("expected error for unknown field")
+	}
+
+	cfg.Subject = "missed"
+	if _, err := newSerializer(context.Background(), cfg); err == nil {
+		t.Error("expected error for missed subject")
+	}
+}
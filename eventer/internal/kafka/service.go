@@ -3,6 +3,7 @@ package kafka
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/sibedge-llc/dp-services/eventer/internal/config"
@@ -47,8 +48,12 @@ func (s *Service) Register(cfg *config.KafkaConfig) (*Producer, error) {
 	producer, ok := s.producers[id]
 	if !ok {
 		var err error
-		if cfg.BootstrapServers == "" {
-			cfg.BootstrapServers = s.Default.GetServers()
+		// Producer is registered by the id of the supplied config, so inherited settings go to its copy
+		c := *cfg
+		cfg = &c
+		// Default producer is registered first, so it has nothing to inherit
+		if s.Default != nil {
+			inheritConfig(cfg, s.Default.GetConfig())
 		}
 		producer, err = NewProducer(s.ctx, id, cfg)
 		if err != nil {
@@ -58,3 +63,52 @@ func (s *Service) Register(cfg *config.KafkaConfig) (*Producer, error) {
 	}
 	return producer, nil
 }
+
+// inheritConfig applies the default settings to the unset ones, security settings are inherited only
+// along with the default servers to not send the default credentials to the servers supplied by the caller
+func inheritConfig(cfg *config.KafkaConfig, defaultCfg *config.KafkaConfig) {
+	isDefaultCluster := cfg.BootstrapServers == ""
+	if isDefaultCluster {
+		cfg.BootstrapServers = defaultCfg.BootstrapServers
+		if cfg.SecurityProtocol == "" {
+			cfg.SecurityProtocol = defaultCfg.SecurityProtocol
+		}
+		if cfg.Sasl == nil {
+			cfg.Sasl = defaultCfg.Sasl
+		}
+		if cfg.Ssl == nil {
+			cfg.Ssl = defaultCfg.Ssl
+		}
+	}
+	if cfg.ClientId == "" {
+		cfg.ClientId = defaultCfg.ClientId
+	}
+	if cfg.TopicConfig == nil {
+		cfg.TopicConfig = defaultCfg.TopicConfig
+	}
+	if cfg.Key == nil {
+		cfg.Key = defaultCfg.Key
+	}
+	if cfg.Partitioner == "" {
+		cfg.Partitioner = defaultCfg.Partitioner
+	}
+	if cfg.SchemaRegistry == nil {
+		cfg.SchemaRegistry = defaultCfg.SchemaRegistry
+	}
+	// Properties of the destination are added to the default ones
+	properties := make(map[string]interface{}, len(defaultCfg.Properties)+len(cfg.Properties))
+	for key, value := range defaultCfg.Properties {
+		if !isDefaultCluster && isSecurityProperty(key) {
+			continue
+		}
+		properties[key] = value
+	}
+	for key, value := range cfg.Properties {
+		properties[key] = value
+	}
+	cfg.Properties = properties
+}
+
+func isSecurityProperty(key string) bool {
+	return key == "security.protocol" || strings.HasPrefix(key, "sasl.") || strings.HasPrefix(key, "ssl.")
+}
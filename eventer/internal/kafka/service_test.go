@@ -0,0 +1,33 @@
+package kafka
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+)
+
+func TestInheritConfig(t *testing.T) {
+	defaultCfg := &config.KafkaConfig{
+		BootstrapServers: "broker:9093",
+		ClientId:         "eventer",
+		SecurityProtocol: "sasl_ssl",
+		Sasl:             &config.KafkaSaslConfig{Mechanism: "PLAIN", Username: "user", Password: "secret"},
+		Ssl:              &config.KafkaSslConfig{CaLocation: "/certs/ca.pem"},
+		Properties:       map[string]interface{}{"linger.ms": float64(5), "sasl.password": "secret"},
+	}
+
+	cfg := &config.KafkaConfig{Topic: "boo"}
+	inheritConfig(cfg, defaultCfg)
+	if cfg.BootstrapServers != "broker:9093" || cfg.Sasl != defaultCfg.Sasl || cfg.Ssl != defaultCfg.Ssl || cfg.SecurityProtocol != "sasl_ssl" ||
+		!reflect.DeepEqual(cfg.Properties, defaultCfg.Properties) {
+		t.Errorf("default cluster settings are not inherited %+v", cfg)
+	}
+
+	cfg = &config.KafkaConfig{BootstrapServers: "other:9092", Topic: "boo"}
+	inheritConfig(cfg, defaultCfg)
+	if cfg.Sasl != nil || cfg.Ssl != nil || cfg.SecurityProtocol != "" || cfg.ClientId != "eventer" ||
+		!reflect.DeepEqual(cfg.Properties, map[string]interface{}{"linger.ms": float64(5)}) {
+		t.Errorf("security settings are inherited by the other cluster %+v", cfg)
+	}
+}
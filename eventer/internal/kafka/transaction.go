@@ -0,0 +1,186 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	c_kafka "github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+	"github.com/sibedge-llc/dp-services/eventer/internal/generator"
+	"github.com/sibedge-llc/dp-services/eventer/internal/metrics"
+)
+
+const (
+	defaultTransactionMessages = 100
+	defaultTransactionIdPrefix = "eventer-"
+	transactionTimeout         = 60 * time.Second
+)
+
+// transaction groups messages of the producer into transactions of the number of messages or the duration
+// and aborts the random part of them
+type transaction struct {
+	lock       sync.Mutex
+	messages   int
+	duration   time.Duration
+	abortRatio float64
+	rand       *rand.Rand
+	seq        uint64
+	count      int
+	isOpen     bool
+	committed  prometheus.Counter
+	aborted    prometheus.Counter
+}
+
+func newTransaction(generatorId uint64, topic string, cfg *config.KafkaTransactionConfig) (*transaction, error) {
+	t := &transaction{
+		messages:   cfg.Messages,
+		abortRatio: cfg.AbortRatio,
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		committed:  metrics.KafkaTransactions.WithLabelValues(fmt.Sprint(generatorId), topic, "committed"),
+		aborted:    metrics.KafkaTransactions.WithLabelValues(fmt.Sprint(generatorId), topic, "aborted"),
+	}
+	if cfg.Duration != "" {
+		duration, err := time.ParseDuration(cfg.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse transaction duration %v: %w", cfg.Duration, err)
+		}
+		t.duration = duration
+	}
+	if t.messages <= 0 && t.duration <= 0 {
+		t.messages = defaultTransactionMessages
+	}
+	if t.abortRatio < 0 || t.abortRatio > 1 {
+		return nil, fmt.Errorf("transaction abort ratio %v is out of [0, 1]", t.abortRatio)
+	}
+	return t, nil
+}
+
+// ForGenerator makes the dedicated transactional producer as the transactional id can't be shared by the generators,
+// it's derived from the generator id. Non transactional producer is shared, so there is no dedicated one
+func (p *Producer) ForGenerator(generatorId uint64) (generator.Destinaton, error) {
+	if p.cfg.Transaction == nil {
+		return nil, nil
+	}
+	txn, err := newTransaction(generatorId, p.topic, p.cfg.Transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := p.cfg.Transaction.IdPrefix
+	if prefix == "" {
+		prefix = defaultTransactionIdPrefix
+	}
+	cfg := *p.cfg
+	cfg.Properties = make(map[string]interface{}, len(p.cfg.Properties)+1)
+	for key, value := range p.cfg.Properties {
+		cfg.Properties[key] = value
+	}
+	cfg.Properties["transactional.id"] = fmt.Sprint(prefix, generatorId)
+
+	producer, err := NewProducer(p.ctx, p.id, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transactional kafka producer: %w", err)
+	}
+	producer.txn = txn
+	return producer, nil
+}
+
+func (p *Producer) initTransactions() error {
+	ctx, cancel := context.WithTimeout(p.ctx, transactionTimeout)
+	defer cancel()
+	err := p.producer.InitTransactions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to init transactions: %w", err)
+	}
+	return nil
+}
+
+// produceInTransaction begins the transaction before the first message and ends it after the last one
+func (p *Producer) produceInTransaction(msg *c_kafka.Message) error {
+	t := p.txn
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if !t.isOpen {
+		err := p.producer.BeginTransaction()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		t.isOpen = true
+		t.count = 0
+		t.seq++
+		if t.duration > 0 {
+			seq := t.seq
+			time.AfterFunc(t.duration, func() {
+				t.lock.Lock()
+				defer t.lock.Unlock()
+				if t.isOpen && t.seq == seq {
+					if err := p.endTransaction(); err != nil {
+						zap.L().Error("failed to end transaction by timeout", zap.String("topic", p.topic), zap.Error(err))
+					}
+				}
+			})
+		}
+	}
+
+	err := p.producer.Produce(msg, nil)
+	if err != nil {
+		return err
+	}
+	t.count++
+	if t.messages > 0 && t.count >= t.messages {
+		return p.endTransaction()
+	}
+	return nil
+}
+
+// flushTransaction ends the open transaction
+func (p *Producer) flushTransaction() {
+	t := p.txn
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if !t.isOpen {
+		return
+	}
+	if err := p.endTransaction(); err != nil {
+		zap.L().Error("failed to end transaction", zap.String("topic", p.topic), zap.Error(err))
+	}
+}
+
+// endTransaction commits or deliberately aborts the open transaction, the transaction is aborted if commit fails
+func (p *Producer) endTransaction() error {
+	t := p.txn
+	t.isOpen = false
+	ctx, cancel := context.WithTimeout(p.ctx, transactionTimeout)
+	defer cancel()
+
+	if t.abortRatio > 0 && t.rand.Float64() < t.abortRatio {
+		err := p.producer.AbortTransaction(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to abort transaction: %w", err)
+		}
+		t.aborted.Inc()
+		return nil
+	}
+
+	err := p.producer.CommitTransaction(ctx)
+	if err == nil {
+		t.committed.Inc()
+		return nil
+	}
+	var kafkaErr c_kafka.Error
+	if errors.As(err, &kafkaErr) && kafkaErr.TxnRequiresAbort() {
+		if abortErr := p.producer.AbortTransaction(ctx); abortErr != nil {
+			return fmt.Errorf("failed to abort transaction after commit error %v: %w", err, abortErr)
+		}
+		t.aborted.Inc()
+	}
+	return fmt.Errorf("failed to commit transaction: %w", err)
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "eventer"
+
+var (
+	EventsComposed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_composed_total",
+		Help:      "Number of events composed by jsonnet schema.",
+	}, []string{"generator"})
+
+	EvaluationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "jsonnet_evaluation_seconds",
+		Help:      "Time of jsonnet schema evaluation.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 16),
+	}, []string{"generator"})
+
+	EventsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_sent_total",
+		Help:      "Number of events sent to destination.",
+	}, []string{"generator", "destination", "target"})
+
+	SendErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "send_errors_total",
+		Help:      "Number of failed event sends to destination.",
+	}, []string{"generator", "destination", "target"})
+
+	NoEventTicks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "no_event_ticks_total",
+		Help:      "Number of generator ticks without composed event.",
+	}, []string{"generator"})
+
+	KafkaDeliveryFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_delivery_failures_total",
+		Help:      "Number of messages failed to be delivered by kafka producer.",
+	}, []string{"generator", "destination_id", "topic"})
+
+	KafkaTransactions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_transactions_total",
+		Help:      "Number of kafka transactions ended by generator, result is committed or aborted.",
+	}, []string{"generator", "topic", "result"})
+
+	HttpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_seconds",
+		Help:      "Time of http destination request, code is the response status or error.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"destination_id", "code"})
+
+	PostgresWriteDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "postgres_write_seconds",
+		Help:      "Time of events write into postgres table, mode is the write mode of the destination.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"destination_id", "table", "mode"})
+)
+
+// DeleteGenerator drops all series of the removed generator
+func DeleteGenerator(generatorId string) {
+	labels := prometheus.Labels{"generator": generatorId}
+	EventsComposed.DeletePartialMatch(labels)
+	EvaluationDuration.DeletePartialMatch(labels)
+	EventsSent.DeletePartialMatch(labels)
+	SendErrors.DeletePartialMatch(labels)
+	NoEventTicks.DeletePartialMatch(labels)
+	KafkaDeliveryFailures.DeletePartialMatch(labels)
+	KafkaTransactions.DeletePartialMatch(labels)
+}
@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDeleteGenerator(t *testing.T) {
+	EventsSent.WithLabelValues("1", "kafka", "boo").Inc()
+	KafkaDeliveryFailures.WithLabelValues("1", "7", "boo").Inc()
+	KafkaDeliveryFailures.WithLabelValues("2", "7", "boo").Inc()
+	PostgresWriteDuration.WithLabelValues("8", "events", "upsert").Observe(0.1)
+
+	if n := testutil.CollectAndCount(PostgresWriteDuration, "eventer_postgres_write_seconds"); n != 1 {
+		t.Errorf("expected postgres write latency by destination, table and mode, got %d series", n)
+	}
+
+	DeleteGenerator("1")
+	if n := testutil.CollectAndCount(EventsSent); n != 0 {
+		t.Errorf("expected sent events of the generator to be deleted, got %d series", n)
+	}
+	if n := testutil.CollectAndCount(KafkaDeliveryFailures); n != 1 {
+		t.Errorf("expected delivery failures of the other generator only, got %d series", n)
+	}
+	if n := testutil.CollectAndCount(PostgresWriteDuration); n != 1 {
+		t.Errorf("destination series must not be deleted along with the generator, got %d series", n)
+	}
+}
@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"sort"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/event"
+)
+
+type ColumnDefinition struct {
+	Name       string `json:"name"`
+	DataType   string `json:"data_type"`
+	IsKey      bool   `json:"is_key"`
+	IsNullable bool   `json:"is_nullable"`
+}
+
+// InferColumns returns columns of the table created along the event
+func InferColumns(obj event.EventObject) ([]ColumnDefinition, error) {
+	columns := make([]ColumnDefinition, 0, len(obj))
+	for k, v := range obj {
+		sqlColumnDef, err := toSqlColumnDefinition(k, v, isKeyColumn(k, v))
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, ColumnDefinition{
+			Name:       k,
+			DataType:   sqlColumnDef.DataType,
+			IsKey:      sqlColumnDef.IsKey,
+			IsNullable: !sqlColumnDef.IsKey,
+		})
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		return columns[i].Name < columns[j].Name
+	})
+	return columns, nil
+}
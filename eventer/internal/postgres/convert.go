@@ -4,446 +4,369 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/lib/pq"
 	"go.uber.org/zap"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/config"
 )
 
+// Converter makes the bind parameter value of the column from the event field value
+type Converter func(in interface{}) (interface{}, error)
+
 type sqlColumnDefinition struct {
 	ColumnDefinition string
+	DataType         string
 	Converter        Converter
 	IsKey            bool
 }
 
-type sqlColumn struct {
-	DataType  string
-	Converter Converter
+var (
+	errNotInteger = errors.New("value is not an integer")
+	errOutOfRange = errors.New("value is out of range")
+)
+
+// widerTypes lists the types the column can be altered to in order, when values don't fit the column type
+var widerTypes = map[string][]string{
+	"int2":   {"int4", "int8", "numeric"},
+	"int4":   {"int8", "numeric"},
+	"int8":   {"numeric"},
+	"float4": {"float8"},
+	"_int2":  {"_int4", "_int8", "_numeric"},
+	"_int4":  {"_int8", "_numeric"},
+	"_int8":  {"_numeric"},
 }
 
-func toSqlColumnDefinition(name string, v interface{}) (*sqlColumnDefinition, error) {
-	isKey := strings.HasSuffix(name, "id") && v != nil
-	column, err := toSqlColumnTypeAndConverter(name, isKey, v)
+// widen returns the narrowest of the wider types fitting the value
+func widen(dataType string, v interface{}) (string, bool) {
+	for _, wider := range widerTypes[normalizeDataType(dataType)] {
+		converter, err := toConverter(wider, true)
+		if err != nil {
+			continue
+		}
+		if _, err = converter(v); err == nil {
+			return wider, true
+		}
+	}
+	return "", false
+}
+
+// normalizeDataType maps sql names of the types to the udt names
+func normalizeDataType(dataType string) string {
+	dataType = strings.ToLower(strings.TrimSpace(dataType))
+	if strings.HasSuffix(dataType, "[]") {
+		return "_" + normalizeDataType(strings.TrimSuffix(dataType, "[]"))
+	}
+	switch dataType {
+	case "smallint":
+		return "int2"
+	case "integer", "int", "serial":
+		return "int4"
+	case "bigint", "bigserial":
+		return "int8"
+	case "real":
+		return "float4"
+	case "timestamp without time zone":
+		return "timestamp"
+	case "timestamp with time zone":
+		return "timestamptz"
+	}
+	return dataType
+}
+
+// isKeyColumn guesses key columns by name when keys are not configured
+func isKeyColumn(name string, v interface{}) bool {
+	return strings.HasSuffix(name, "id") && v != nil
+}
+
+func toSqlColumnDefinition(name string, v interface{}, isKey bool) (*sqlColumnDefinition, error) {
+	dataType := toSqlDataType(name, isKey, v)
+	converter, err := toConverter(dataType, !isKey)
 	if err != nil {
 		return nil, err
 	}
 	var columnDefinition string
 	if isKey {
-		columnDefinition = fmt.Sprintf("%s %s", name, column.DataType)
+		columnDefinition = fmt.Sprintf("%s %s", pq.QuoteIdentifier(name), dataType)
+	} else {
+		columnDefinition = fmt.Sprintf("%s %s NULL", pq.QuoteIdentifier(name), dataType)
+	}
+	return &sqlColumnDefinition{
+		ColumnDefinition: columnDefinition,
+		DataType:         dataType,
+		Converter:        converter,
+		IsKey:            isKey,
+	}, nil
+}
+
+var sqlDataTypeRegexp = regexp.MustCompile(`^[a-z][a-z0-9_ ]*(\(\s*\d+\s*(,\s*\d+\s*)?\))?(\[\])?$`)
+
+var sqlDefaultRegexp = regexp.MustCompile(`^(-?\d+(\.\d+)?|true|false|null|now\(\)|current_timestamp|current_date|current_time|localtimestamp|gen_random_uuid\(\))$`)
+
+// toSqlDefault keeps numbers, booleans, null and the functions of the current time and random uuid as is,
+// any other default is the string literal
+func toSqlDefault(value string) string {
+	expression := strings.ToLower(strings.TrimSpace(value))
+	if sqlDefaultRegexp.MatchString(expression) {
+		return expression
+	}
+	return pq.QuoteLiteral(value)
+}
+
+func toMappedSqlColumnDefinition(name string, column config.PostgresColumnConfig, isKey bool) (*sqlColumnDefinition, error) {
+	dataType := strings.ToLower(strings.TrimSpace(column.Type))
+	if !sqlDataTypeRegexp.MatchString(dataType) {
+		return nil, fmt.Errorf("invalid type %q of column %s", column.Type, name)
+	}
+	isNullable := !isKey
+	if column.Nullable != nil {
+		isNullable = *column.Nullable && !isKey
+	}
+	converter, err := toConverter(dataType, isNullable)
+	if err != nil {
+		return nil, err
+	}
+	columnDefinition := fmt.Sprintf("%s %s", pq.QuoteIdentifier(name), dataType)
+	if isNullable {
+		columnDefinition += " NULL"
 	} else {
-		columnDefinition = fmt.Sprintf("%s %s NULL", name, column.DataType)
+		columnDefinition += " NOT NULL"
+	}
+	if column.Default != "" {
+		columnDefinition += " DEFAULT " + toSqlDefault(column.Default)
 	}
 	return &sqlColumnDefinition{
 		ColumnDefinition: columnDefinition,
-		Converter:        column.Converter,
+		DataType:         dataType,
+		Converter:        converter,
 		IsKey:            isKey,
 	}, nil
 }
 
-func toSqlColumnTypeAndConverter(name string, isKey bool, v interface{}) (sqlColumn, error) {
+func toSqlDataType(name string, isKey bool, v interface{}) string {
 	switch t := v.(type) {
 	case map[string]interface{}:
-		converter, err := toConverterByValue("jsonb", isKey, t)
-		return sqlColumn{DataType: "jsonb", Converter: converter}, err
+		return "jsonb"
 	case string:
-		dataType := "text"
 		if strings.HasPrefix(name, "time") || strings.HasSuffix(name, "time") {
-			dataType = "timestamp"
+			return "timestamp"
 		}
-		converter, err := toConverterByValue(dataType, isKey, t)
-		return sqlColumn{DataType: dataType, Converter: converter}, err
+		return "text"
 	case float64:
-		dataType := "decimal"
-		if strings.HasSuffix(name, "id") || t == float64(int64(t)) {
-			dataType = "integer"
+		if strings.HasSuffix(name, "id") || t == math.Trunc(t) {
+			return "bigint"
 		}
-		converter, err := toConverterByValue(dataType, isKey, t)
-		return sqlColumn{DataType: dataType, Converter: converter}, err
+		return "decimal"
+	case bool:
+		return "boolean"
 	case nil:
-		converter, err := toConverterByValue("text", isKey, t)
-		return sqlColumn{DataType: "text", Converter: converter}, err
+		return "text"
 	case []interface{}:
-		var v0 interface{}
+		var v0 interface{} = ""
 		if len(t) > 0 {
 			v0 = t[0]
-		} else {
-			v0 = ""
 		}
-		column, err := toSqlColumnTypeAndConverter(name, false, v0)
-		if err != nil {
-			return sqlColumn{}, err
+		switch v0.(type) {
+		case map[string]interface{}, []interface{}:
+			// Nested structures are kept as json documents
+			return "jsonb"
 		}
-		if strings.HasPrefix(column.DataType, "[]") {
-			return sqlColumn{
-				DataType: "[]text",
-				Converter: func(in interface{}) (string, error) {
-					strVals := make([]string, 0, len(in.([]interface{})))
-					for _, v := range in.([]interface{}) {
-						d, err := json.Marshal(v)
-						if err != nil {
-							return "", err
-						}
-						str := string(d)
-						strVals = append(strVals, toSqlString(str))
-					}
-					return fmt.Sprintf("ARRAY[%s]", strings.Join(strVals, ",")), nil
-				},
-			}, nil
-		}
-		dataType := fmt.Sprintf("[]%s", column.DataType)
-		converter, err := toConverterByValue(dataType, isKey, t)
-		return sqlColumn{DataType: dataType, Converter: converter}, err
+		return toSqlDataType(name, false, v0) + "[]"
 	default:
 		zap.L().Error("unsupported type", zap.String("type", fmt.Sprintf("%T", t)))
+		return "text"
+	}
+}
 
+// toConverter makes the converter by either sql data type or udt name of the column
+func toConverter(dataType string, isNullable bool) (Converter, error) {
+	dataType = strings.ToLower(dataType)
+	if strings.HasSuffix(dataType, "[]") || strings.HasPrefix(dataType, "_") {
+		elemType := strings.TrimPrefix(strings.TrimSuffix(dataType, "[]"), "_")
+		elemConverter, err := toConverter(elemType, true)
+		if err != nil {
+			return nil, err
+		}
+		return withNull(toArray(elemConverter), isNullable, "{}"), nil
 	}
 
-	converter, err := toConverterByValue("text", isKey, nil)
-	return sqlColumn{DataType: "text", Converter: converter}, err
+	if i := strings.Index(dataType, "("); i > 0 {
+		dataType = strings.TrimSpace(dataType[:i])
+	}
+	switch dataType {
+	case "smallint", "int2":
+		return withNull(toIntRange(math.MinInt16, math.MaxInt16), isNullable, int64(0)), nil
+	case "integer", "int", "int4", "serial":
+		return withNull(toIntRange(math.MinInt32, math.MaxInt32), isNullable, int64(0)), nil
+	case "bigint", "int8", "bigserial":
+		return withNull(toInt, isNullable, int64(0)), nil
+	case "real", "float4":
+		return withNull(toFloat32, isNullable, float64(0)), nil
+	case "decimal", "numeric", "double precision", "float8":
+		return withNull(toNumber, isNullable, float64(0)), nil
+	case "boolean", "bool":
+		return withNull(toBool, isNullable, false), nil
+	case "text", "varchar", "character varying", "char", "character", "bpchar", "uuid", "inet", "cidr":
+		return withNull(toText, isNullable, ""), nil
+	case "timestamp", "timestamp without time zone", "timestamptz", "timestamp with time zone", "date":
+		return withNull(toTime, isNullable, time.Unix(0, 0).UTC()), nil
+	case "json", "jsonb":
+		return withNull(toJson, isNullable, "{}"), nil
+	default:
+		zap.L().Error("unsupported column type", zap.String("data_type", dataType))
+		return nil, fmt.Errorf("unsupported column type: %s", dataType)
+	}
 }
 
-func toConverterByColumnDef(colDef sqlColumnType, v interface{}) (Converter, error) {
-	switch colDef.DataType {
-	case "integer", "numeric":
-		switch t := v.(type) {
-		case string:
-			_, err := strconv.ParseFloat(t, 64)
-			if err != nil {
-				return nil, err
+// withNull keeps nulls for nullable columns and replaces them by the default value otherwise
+func withNull(converter Converter, isNullable bool, defaultValue interface{}) Converter {
+	return func(in interface{}) (interface{}, error) {
+		if in == nil {
+			if isNullable {
+				return nil, nil
 			}
-			return func(in interface{}) (string, error) {
-				return in.(string), nil
-			}, nil
-		case map[string]interface{}:
-			return nil, errors.New("cannot convert map to number/numeric value")
-		case []interface{}:
-			return nil, errors.New("cannot convert array(slice) to number/numeric value")
-		case nil:
-			return toConverterByColumnDefForNil(colDef)
-		case bool:
-			return func(in interface{}) (string, error) {
-				if in.(bool) {
-					return "1", nil
-				} else {
-					return "0", nil
-				}
-			}, nil
-		case float64:
-			return func(in interface{}) (string, error) {
-				return fmt.Sprint(in), nil
-			}, nil
+			return defaultValue, nil
 		}
-	case "boolean":
-		switch t := v.(type) {
-		case string:
-			_, err := strconv.ParseBool(t)
-			if err != nil {
-				return nil, fmt.Errorf("cannot convert string %s to bool: %w", t, err)
-			}
-			return func(in interface{}) (string, error) {
-				return in.(string), nil
-			}, nil
-		case map[string]interface{}:
-			return nil, errors.New("cannot convert map to boolean value")
-		case []interface{}:
-			return nil, errors.New("cannot convert array(slice) to boolean value")
-		case nil:
-			return toConverterByColumnDefForNil(colDef)
-		case bool:
-			return func(in interface{}) (string, error) {
-				if in.(bool) {
-					return toSqlString("t"), nil
-				} else {
-					return toSqlString("f"), nil
-				}
-			}, nil
-		case float64:
-			return func(in interface{}) (string, error) {
-				if int(in.(float64)) == 0 {
-					return toSqlString("f"), nil
-				}
-				return toSqlString("t"), nil
-			}, nil
+		return converter(in)
+	}
+}
+
+func toInt(in interface{}) (interface{}, error) {
+	switch t := in.(type) {
+	case float64:
+		if t != math.Trunc(t) {
+			return nil, fmt.Errorf("%w: %v", errNotInteger, t)
 		}
-	case "array":
-		switch t := v.(type) {
-		case string:
-			switch colDef.UdtName {
-			case "_text", "_varchar":
-				return func(in interface{}) (string, error) {
-					return toSqlString("{", in.(string), "}"), nil
-				}, nil
-			default:
-				return func(in interface{}) (string, error) {
-					return fmt.Sprintf("{%v}", in.(string)), nil
-				}, nil
-			}
-		case map[string]interface{}:
-			_, err := json.Marshal(t)
-			if err != nil {
-				return nil, fmt.Errorf("cannot convert map value as json string representation: %w", err)
-			}
-			switch colDef.UdtName {
-			case "_text", "_varchar":
-				return func(in interface{}) (string, error) {
-					v, err := json.Marshal(t)
-					if err != nil {
-						return "", err
-					}
-					return toSqlString("{", string(v), "}"), nil
-				}, nil
-			default:
-				return func(in interface{}) (string, error) {
-					v, err := json.Marshal(t)
-					if err != nil {
-						return "", err
-					}
-					return fmt.Sprintf("{%v}", v), nil
-				}, nil
-			}
-		case []interface{}:
-			switch colDef.UdtName {
-			case "_text", "_varchar":
-				return func(in interface{}) (string, error) {
-					vals := make([]string, len(in.([]interface{})))
-					for i, vi := range in.([]interface{}) {
-						v, err := json.Marshal(vi)
-						if err != nil {
-							return "", err
-						}
-						vals[i] = toSqlString(string(v))
-					}
-					return fmt.Sprintf("{%s}", strings.Join(vals, ",")), nil
-				}, nil
-			default:
-				return func(in interface{}) (string, error) {
-					v, err := json.Marshal(in)
-					if err != nil {
-						return "", err
-					}
-					return fmt.Sprintf("ARRAY%s", string(v)), nil
-				}, nil
-			}
-		case nil:
-			return toConverterByColumnDefForNil(colDef)
-		case bool:
-			switch colDef.UdtName {
-			case "_text", "_varchar":
-				return func(in interface{}) (string, error) {
-					return toSqlString("{", fmt.Sprintf("%v", in.(bool)), "}"), nil
-				}, nil
-			default:
-				return func(in interface{}) (string, error) {
-					return fmt.Sprintf("{%v}", in.(bool)), nil
-				}, nil
-			}
-		case float64:
-			switch colDef.UdtName {
-			case "_text", "_varchar":
-				return func(in interface{}) (string, error) {
-					return toSqlString("{", fmt.Sprintf("%v", in.(float64)), "}"), nil
-				}, nil
-			default:
-				return func(in interface{}) (string, error) {
-					return fmt.Sprintf("{%v}", in.(float64)), nil
-				}, nil
-			}
+		if t < math.MinInt64 || t >= math.MaxInt64 {
+			return nil, fmt.Errorf("%w: %v", errOutOfRange, t)
 		}
-	case "text", "varchar":
-		switch v.(type) {
-		case string:
-			return func(in interface{}) (string, error) {
-				return toSqlString(in.(string)), nil
-			}, nil
-		case map[string]interface{}:
-			return func(in interface{}) (string, error) {
-				v, err := json.Marshal(in)
-				if err != nil {
-					return "", err
-				}
-				return toSqlString(string(v)), nil
-			}, nil
-		case []interface{}:
-			return func(in interface{}) (string, error) {
-				v, err := json.Marshal(in)
-				if err != nil {
-					return "", err
-				}
-				return toSqlString(string(v)), nil
-			}, nil
-		case nil:
-			return toConverterByColumnDefForNil(colDef)
-		case bool:
-			return func(in interface{}) (string, error) {
-				return toSqlString(fmt.Sprint(in)), nil
-			}, nil
-		case float64:
-			return func(in interface{}) (string, error) {
-				return toSqlString(fmt.Sprint(in)), nil
-			}, nil
+		return int64(t), nil
+	case string:
+		return strconv.ParseInt(t, 10, 64)
+	case bool:
+		if t {
+			return int64(1), nil
 		}
-	case "timestamp":
-		switch v.(type) {
-		case string:
-			return func(in interface{}) (string, error) {
-				return toSqlString(in.(string)), nil
-			}, nil
-		case map[string]interface{}:
-			return nil, errors.New("cannot convert map to timestamp value")
-		case []interface{}:
-			return nil, errors.New("cannot convert array/slice to timestamp value")
-		case nil:
-			return toConverterByColumnDefForNil(colDef)
-		case bool:
-			return nil, errors.New("cannot convert boolean to timestamp value")
-		case float64:
-			return func(in interface{}) (string, error) {
-				return fmt.Sprintf("to_timestamp(%d)", int(in.(float64))), nil
-			}, nil
+		return int64(0), nil
+	}
+	return nil, fmt.Errorf("cannot convert %T to integer value", in)
+}
+
+func toIntRange(min int64, max int64) Converter {
+	return func(in interface{}) (interface{}, error) {
+		v, err := toInt(in)
+		if err != nil {
+			return nil, err
 		}
-	case "json", "jsonb":
-		switch v.(type) {
-		case string:
-			return func(in interface{}) (string, error) {
-				return toSqlString(in.(string)), nil
-			}, nil
-		case map[string]interface{}:
-			return func(in interface{}) (string, error) {
-				v, err := json.Marshal(in)
-				if err != nil {
-					return "", err
-				}
-				return toSqlString(string(v)), nil
-			}, nil
-		case []interface{}:
-			return func(in interface{}) (string, error) {
-				v, err := json.Marshal(in)
-				if err != nil {
-					return "", err
-				}
-				return toSqlString(string(v)), nil
-			}, nil
-		case nil:
-			return toConverterByColumnDefForNil(colDef)
-		case bool:
-			return func(in interface{}) (string, error) {
-				return toSqlString(fmt.Sprint(in)), nil
-			}, nil
-		case float64:
-			return func(in interface{}) (string, error) {
-				return toSqlString(fmt.Sprint(in)), nil
-			}, nil
+		if i := v.(int64); i < min || i > max {
+			return nil, fmt.Errorf("%w: %d", errOutOfRange, i)
 		}
+		return v, nil
 	}
-	return nil, fmt.Errorf("unsupported column type: %s(%s) and data type: %T", colDef.DataType, colDef.UdtName, v)
 }
 
-func toConverterByValue(dataType string, isKey bool, v interface{}) (Converter, error) {
-	switch t := v.(type) {
-	case nil:
-		return toConverterByColumnDefForNil(sqlColumnType{DataType: dataType, IsNullable: !isKey})
-	case string:
-		return func(in interface{}) (string, error) {
-			return toSqlString(in.(string)), nil
-		}, nil
+func toFloat32(in interface{}) (interface{}, error) {
+	v, err := toNumber(in)
+	if err != nil {
+		return nil, err
+	}
+	if f, ok := v.(float64); ok && math.Abs(f) > math.MaxFloat32 {
+		return nil, fmt.Errorf("%w: %v", errOutOfRange, f)
+	}
+	return v, nil
+}
+
+func toNumber(in interface{}) (interface{}, error) {
+	switch t := in.(type) {
 	case float64:
-		if isKey {
-			return func(in interface{}) (string, error) {
-				return fmt.Sprint(int64(in.(float64))), nil
-			}, nil
+		return t, nil
+	case string:
+		// Numeric string is passed as is to keep the precision
+		_, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			return nil, err
 		}
-		return func(in interface{}) (string, error) {
-			return fmt.Sprint(in.(float64)), nil
-		}, nil
-	case []byte:
-		return func(in interface{}) (string, error) {
-			return fmt.Sprintf("'\\x%x'", in), nil
-		}, nil
+		return t, nil
 	case bool:
-		return func(in interface{}) (string, error) {
-			if in.(bool) {
-				return toSqlString("t"), nil
-			}
-			return toSqlString("f"), nil
-		}, nil
-	case map[string]interface{}:
-		return func(in interface{}) (string, error) {
-			data, err := json.Marshal(in)
-			if err != nil {
-				return "", err
-			}
-			return toSqlString(string(data)), nil
-		}, nil
-	case []interface{}:
-		return func(in interface{}) (string, error) {
-			data, err := json.Marshal(t)
-			if err != nil {
-				return "", err
-			}
-			return fmt.Sprintf("ARRAY%s", string(data)), nil
-		}, nil
-	default:
-		zap.L().Error("usupported type", zap.String("type", fmt.Sprintf("%T", t)))
-		return nil, fmt.Errorf("usupported type: %T", t)
+		if t {
+			return float64(1), nil
+		}
+		return float64(0), nil
 	}
+	return nil, fmt.Errorf("cannot convert %T to numeric value", in)
 }
 
-func toSqlString(vals ...string) string {
-	var (
-		prefix string
-		val    string
-		suffix string
-	)
-	switch len(vals) {
-	case 0:
-		return ""
-	case 1:
-		val = vals[0]
-	case 2:
-		prefix = vals[0]
-		val = vals[1]
-	default:
-		prefix = vals[0]
-		val = vals[1]
-		suffix = strings.Join(vals[2:], "")
+func toBool(in interface{}) (interface{}, error) {
+	switch t := in.(type) {
+	case bool:
+		return t, nil
+	case string:
+		v, err := strconv.ParseBool(t)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert string %s to bool: %w", t, err)
+		}
+		return v, nil
+	case float64:
+		return t != 0, nil
 	}
+	return nil, fmt.Errorf("cannot convert %T to boolean value", in)
+}
+
+func toText(in interface{}) (interface{}, error) {
+	switch t := in.(type) {
+	case string:
+		return t, nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	}
+	return toJson(in)
+}
 
-	return fmt.Sprintf("%s'%s'%s", prefix, strings.ReplaceAll(val, "'", "''"), suffix)
+// toTime treats numbers as unix time in seconds, strings are parsed by postgres
+func toTime(in interface{}) (interface{}, error) {
+	switch t := in.(type) {
+	case float64:
+		sec, frac := math.Modf(t)
+		return time.Unix(int64(sec), int64(frac*float64(time.Second))).UTC(), nil
+	case string:
+		return t, nil
+	}
+	return nil, fmt.Errorf("cannot convert %T to timestamp value", in)
 }
 
-func toConverterByColumnDefForNil(colDef sqlColumnType) (Converter, error) {
-	if colDef.IsNullable {
-		return func(in interface{}) (string, error) {
-			return "NULL", nil
-		}, nil
+func toJson(in interface{}) (interface{}, error) {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert value to json: %w", err)
 	}
-	switch colDef.DataType {
-	case "integer", "numeric":
-		return func(in interface{}) (string, error) {
-			return "0", nil
-		}, nil
-	case "boolean":
-		return func(in interface{}) (string, error) {
-			return "'f'", nil
-		}, nil
-	case "array":
-		return func(in interface{}) (string, error) {
-			return "{}", nil
-		}, nil
-	case "text", "varchar":
-		return func(in interface{}) (string, error) {
-			return "", nil
-		}, nil
-	case "json", "jsonb":
-		return func(in interface{}) (string, error) {
-			return "{}", nil
-		}, nil
-	case "timestamp":
-		return func(in interface{}) (string, error) {
-			return "to_timestamp(0)", nil
-		}, nil
-	default:
-		zap.L().Error("usupported type to convert nil to default", zap.String("data_type", colDef.DataType), zap.String("udt_name", colDef.UdtName))
-		return nil, fmt.Errorf("usupported type to convert nil to default: %s (%s)", colDef.DataType, colDef.UdtName)
+	return string(data), nil
+}
+
+func toArray(elemConverter Converter) Converter {
+	return func(in interface{}) (interface{}, error) {
+		values, ok := in.([]interface{})
+		if !ok {
+			values = []interface{}{in}
+		}
+		elems := make([]interface{}, len(values))
+		for i, value := range values {
+			elem, err := elemConverter(value)
+			if err != nil {
+				return nil, err
+			}
+			// Array elements are sent as text, so time is formatted the way postgres parses
+			if t, ok := elem.(time.Time); ok {
+				elem = t.Format(time.RFC3339Nano)
+			}
+			elems[i] = elem
+		}
+		return pq.GenericArray{A: elems}, nil
 	}
 }
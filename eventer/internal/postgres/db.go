@@ -11,11 +11,13 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"go.uber.org/zap"
 
 	"github.com/sibedge-llc/dp-services/eventer/internal/config"
 	"github.com/sibedge-llc/dp-services/eventer/internal/event"
+	"github.com/sibedge-llc/dp-services/eventer/internal/metrics"
+	"github.com/sibedge-llc/dp-services/eventer/internal/utils"
 )
 
 var (
@@ -23,29 +25,26 @@ var (
 	selectTableColumnsSql string
 )
 
-type Converter func(in interface{}) (string, error)
-
-type tableSchema struct {
-	Sql            string
-	Converters     map[string]Converter
-	Columns        []string
-	ColumnsIndices map[string]int
-}
-
 type Db struct {
-	ctx     context.Context
-	db      *sqlx.DB
-	timeout time.Duration
-	cfg     *config.PostgresConfig
-	id      uint64
-	lock    sync.Mutex
-	schema  *tableSchema
-}
-
-type sqlColumnType struct {
-	DataType   string
-	UdtName    string
-	IsNullable bool
+	ctx           context.Context
+	db            *sqlx.DB
+	timeout       time.Duration
+	cfg           *config.PostgresConfig
+	id            uint64
+	schema        *tableSchema
+	fields        event.EventObject
+	partitions    map[string]bool
+	batchLock     sync.Mutex
+	batch         [][]interface{}
+	reports       [][]event.DeliveryReporter
+	batchKeys     map[string]int
+	stmts         map[int]*sqlx.Stmt
+	batchSize     int
+	flushInterval time.Duration
+	retry         utils.RetryPolicy
+	write         func(rows [][]interface{}) error
+	childLock     sync.Mutex
+	children      map[string]*Db
 }
 
 func NewDb(ctx context.Context, id uint64, cfg *config.PostgresConfig, timeout time.Duration) (*Db, error) {
@@ -54,10 +53,73 @@ func NewDb(ctx context.Context, id uint64, cfg *config.PostgresConfig, timeout t
 		return nil, errors.New("table name is empty or not provided")
 	}
 
-	db, err := sqlx.ConnectContext(ctx, "postgres", getDataSource(cfg))
+	switch cfg.Mode {
+	case "", config.PostgresModeUpsert, config.PostgresModeAppend, config.PostgresModeIgnore:
+	case config.PostgresModeScd2:
+		for _, key := range cfg.Keys {
+			if key == validFromColumn || key == validToColumn {
+				return nil, fmt.Errorf("column %s can't be a key in %s mode", key, cfg.Mode)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown write mode: %s", cfg.Mode)
+	}
+
+	switch cfg.Evolution {
+	case "", config.PostgresEvolutionEvolve, config.PostgresEvolutionIgnore, config.PostgresEvolutionReject:
+	default:
+		return nil, fmt.Errorf("unknown schema evolution policy: %s", cfg.Evolution)
+	}
+
+	if cfg.Partition != nil {
+		if cfg.Partition.Field == "" {
+			return nil, errors.New("partition field is empty or not provided")
+		}
+		switch cfg.Partition.Interval {
+		case config.PostgresPartitionDaily, config.PostgresPartitionMonthly:
+		default:
+			return nil, fmt.Errorf("unknown partition interval: %s", cfg.Partition.Interval)
+		}
+	}
+
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	var flushInterval time.Duration
+	if cfg.FlushInterval != "" {
+		var err error
+		flushInterval, err = time.ParseDuration(cfg.FlushInterval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse flush interval %v: %w", cfg.FlushInterval, err)
+		}
+	}
+
+	retry, err := utils.NewRetryPolicy(cfg.RetryAttempts, cfg.RetryBackoff, cfg.RetryMaxBackoff, isRetryable)
+	if err != nil {
+		return nil, err
+	}
+	var connMaxLifetime time.Duration
+	if cfg.ConnMaxLifetime != "" {
+		connMaxLifetime, err = time.ParseDuration(cfg.ConnMaxLifetime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse connection max lifetime %v: %w", cfg.ConnMaxLifetime, err)
+		}
+	}
+	dataSource, err := getDataSource(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sqlx.ConnectContext(ctx, "postgres", dataSource)
 	if err != nil {
 		return nil, fmt.Errorf("connection failed: %w", err)
 	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	if cfg.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(connMaxLifetime)
 
 	err = db.PingContext(ctx)
 
@@ -65,13 +127,32 @@ func NewDb(ctx context.Context, id uint64, cfg *config.PostgresConfig, timeout t
 		return nil, fmt.Errorf("ping db failed: %w", err)
 	}
 
-	return &Db{
-		ctx:     ctx,
-		db:      db,
-		timeout: timeout,
-		cfg:     cfg,
-		id:      id,
-	}, nil
+	s := newDb(ctx, id, cfg, timeout, db, batchSize, flushInterval)
+	s.retry = retry
+	if batchSize > 1 && flushInterval > 0 {
+		go s.run(ctx)
+	}
+	return s, nil
+}
+
+func newDb(ctx context.Context, id uint64, cfg *config.PostgresConfig, timeout time.Duration, db *sqlx.DB, batchSize int, flushInterval time.Duration) *Db {
+	s := &Db{
+		ctx:           ctx,
+		db:            db,
+		timeout:       timeout,
+		cfg:           cfg,
+		id:            id,
+		batch:         make([][]interface{}, 0, batchSize),
+		reports:       make([][]event.DeliveryReporter, 0, batchSize),
+		batchKeys:     make(map[string]int, batchSize),
+		stmts:         make(map[int]*sqlx.Stmt, 2),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		children:      make(map[string]*Db),
+		partitions:    make(map[string]bool),
+	}
+	s.write = s.exec
+	return s
 }
 
 func (db *Db) GetConfig() *config.PostgresConfig {
@@ -79,6 +160,8 @@ func (db *Db) GetConfig() *config.PostgresConfig {
 }
 
 func (db *Db) Close() {
+	db.Flush()
+	db.closeStmts()
 	err := db.db.Close()
 	if err != nil {
 		zap.L().Error("failed to close postgres", zap.Error(err))
@@ -86,30 +169,263 @@ func (db *Db) Close() {
 }
 
 func (db *Db) Flush() {
+	db.batchLock.Lock()
+	db.flush()
+	db.batchLock.Unlock()
+	for _, child := range db.getChildren() {
+		child.Flush()
+	}
+}
+
+func (db *Db) closeStmts() {
+	db.batchLock.Lock()
+	for _, stmt := range db.stmts {
+		stmt.Close()
+	}
+	db.batchLock.Unlock()
+	for _, child := range db.getChildren() {
+		child.closeStmts()
+	}
 }
 
 func (db *Db) GetId() uint64 {
 	return db.id
 }
 
+func (db *Db) GetType() string {
+	return event.DestinationTypePostgres
+}
+
+func (db *Db) GetTarget() string {
+	return db.cfg.Table
+}
+
 func (db *Db) Init(evt *event.Event) error {
-	return db.updateOrCreateTableSchema(evt.Object)
+	return db.init(evt.Object)
+}
+
+// IsBuffered tells the generator that the events are counted by the delivery reports once they're written
+func (db *Db) IsBuffered() bool {
+	return true
 }
 
 func (db *Db) Send(evt *event.Event) error {
-	query, err := db.composeQuery(evt.Object)
+	return db.send(evt.Object, evt.Delivery)
+}
+
+func (db *Db) init(obj event.EventObject) error {
+	flat, arrays := flatten(obj, db.cfg.Flatten)
+	err := db.ensureSchema(flat)
 	if err != nil {
-		return fmt.Errorf("failed to compose query along event: %w", err)
+		return err
 	}
+	for path, elems := range arrays {
+		child, rows, err := db.childRows(path, flat, elems)
+		if err != nil {
+			return err
+		}
+		if len(rows) > 0 {
+			err = child.init(rows[0])
+			if err != nil {
+				return fmt.Errorf("failed to init child table %s: %w", child.cfg.Table, err)
+			}
+		}
+	}
+	return nil
+}
 
-	_, err = db.db.ExecContext(db.ctx, query)
+// send adds the rows of the event, the event is reported once its row of the parent table is written
+func (db *Db) send(obj event.EventObject, report event.DeliveryReporter) error {
+	flat, arrays := flatten(obj, db.cfg.Flatten)
+	err := db.add(flat, report)
+	if err != nil {
+		return err
+	}
+	for path, elems := range arrays {
+		child, rows, err := db.childRows(path, flat, elems)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			err = child.send(row, nil)
+			if err != nil {
+				return fmt.Errorf("failed to send to child table %s: %w", child.cfg.Table, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (db *Db) ensureSchema(obj event.EventObject) error {
+	db.batchLock.Lock()
+	defer db.batchLock.Unlock()
+	if db.schema != nil && !db.hasNewFields(obj) {
+		return nil
+	}
+	return db.evolve(obj)
+}
+
+func (db *Db) add(obj event.EventObject, report event.DeliveryReporter) error {
+	db.batchLock.Lock()
+	defer db.batchLock.Unlock()
+
+	if db.schema == nil || db.hasNewFields(obj) {
+		err := db.evolve(obj)
+		if err != nil {
+			return err
+		}
+	}
+	row, key, err := db.composeRow(obj)
+	if err != nil && db.evolution() == config.PostgresEvolutionEvolve {
+		// Column may be widened to fit the value
+		err = db.evolve(obj)
+		if err != nil {
+			return err
+		}
+		row, key, err = db.composeRow(obj)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to perform upsert the event: %w", err)
+		return fmt.Errorf("failed to compose query along event: %w", err)
+	}
+	if db.schema.PartitionIndex >= 0 {
+		err = db.ensurePartition(row[db.schema.PartitionIndex])
+		if err != nil {
+			return err
+		}
+	}
+
+	// Every version is kept in scd2 mode, so the pending versions are written before the next version of the same key,
+	// versions of one statement would get the same valid_from
+	if _, ok := db.batchKeys[key]; ok && db.schema.Mode == config.PostgresModeScd2 {
+		db.flush()
+	}
+
+	// Upsert can't affect the same row twice in one statement, the latest event wins unless conflicts are ignored
+	// Replaced event is reported along with the row replacing it
+	i, ok := db.batchKeys[key]
+	if ok {
+		if db.schema.Mode != config.PostgresModeIgnore {
+			db.batch[i] = row
+		}
+	} else {
+		i = len(db.batch)
+		if key != "" {
+			db.batchKeys[key] = i
+		}
+		db.batch = append(db.batch, row)
+		db.reports = append(db.reports, nil)
+	}
+	if report != nil {
+		db.reports[i] = append(db.reports[i], report)
+	}
+	if len(db.batch) >= db.batchSize {
+		db.flush()
 	}
 	return nil
 }
 
-func getDataSource(cfg *config.PostgresConfig) string {
+func (db *Db) SendBatch(evts []*event.Event) error {
+	for _, evt := range evts {
+		err := db.Send(evt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *Db) run(ctx context.Context) {
+	ticker := time.NewTicker(db.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			db.Flush()
+		}
+	}
+}
+
+// flush writes the pending rows and reports the result to the events of every row
+func (db *Db) flush() {
+	if len(db.batch) == 0 {
+		return
+	}
+	batch, reports := db.batch, db.reports
+	db.batch = make([][]interface{}, 0, db.batchSize)
+	db.reports = make([][]event.DeliveryReporter, 0, db.batchSize)
+	for key := range db.batchKeys {
+		delete(db.batchKeys, key)
+	}
+
+	// Number of bind parameters of the statement is limited
+	unsent, err := writeChunks(db.ctx, batch, maxBindParams/len(db.schema.Columns), db.write, func(offset int, n int, err error) {
+		for _, rowReports := range reports[offset : offset+n] {
+			for _, report := range rowReports {
+				if err != nil {
+					report.Failed(err)
+				} else {
+					report.Delivered()
+				}
+			}
+		}
+	})
+	if err != nil {
+		zap.L().Error("failed to write events", zap.String("table", db.cfg.Table), zap.Int("unsent", unsent), zap.Int("total", len(batch)), zap.Error(err))
+	}
+}
+
+// writeChunks writes the rows by chunks of the size, a failed chunk doesn't prevent the rest from being written.
+// Result of every chunk is passed to done along with its offset, the number of unsent rows is returned along with the last error.
+func writeChunks(ctx context.Context, rows [][]interface{}, size int, write func([][]interface{}) error, done func(offset int, n int, err error)) (int, error) {
+	var (
+		unsent  int
+		lastErr error
+	)
+	for offset := 0; offset < len(rows); offset += size {
+		if ctx.Err() != nil {
+			done(offset, len(rows)-offset, ctx.Err())
+			return unsent + len(rows) - offset, ctx.Err()
+		}
+		if size > len(rows)-offset {
+			size = len(rows) - offset
+		}
+		err := write(rows[offset : offset+size])
+		if err != nil {
+			unsent += size
+			lastErr = err
+		}
+		done(offset, size, err)
+	}
+	return unsent, lastErr
+}
+
+func (db *Db) exec(rows [][]interface{}) error {
+	args := make([]interface{}, 0, len(rows)*len(db.schema.Columns))
+	for _, row := range rows {
+		args = append(args, row...)
+	}
+
+	return db.retry.Do(db.ctx, "postgres write", func() error {
+		stmt, ok := db.stmts[len(rows)]
+		if !ok {
+			var err error
+			stmt, err = db.db.PreparexContext(db.ctx, db.schema.Sql(len(rows)))
+			if err != nil {
+				return fmt.Errorf("failed to prepare statement: %w", err)
+			}
+			db.stmts[len(rows)] = stmt
+		}
+
+		started := time.Now()
+		_, err := stmt.ExecContext(db.ctx, args...)
+		metrics.PostgresWriteDuration.WithLabelValues(fmt.Sprint(db.id), db.cfg.Table, db.schema.Mode).Observe(time.Since(started).Seconds())
+		return err
+	}, zap.String("table", db.cfg.Table))
+}
+
+func getDataSource(cfg *config.PostgresConfig) (string, error) {
 	parts := make([]string, 0, 6)
 	if cfg.Host != "" {
 		parts = append(parts, fmt.Sprintf("host=%s", cfg.Host))
@@ -130,29 +446,73 @@ func getDataSource(cfg *config.PostgresConfig) string {
 	if !cfg.Ssl {
 		parts = append(parts, "sslmode=disable")
 	}
-	return strings.Join(parts, " ")
+	if cfg.StatementTimeout != "" {
+		timeout, err := time.ParseDuration(cfg.StatementTimeout)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse statement timeout %v: %w", cfg.StatementTimeout, err)
+		}
+		parts = append(parts, fmt.Sprintf("statement_timeout=%d", timeout.Milliseconds()))
+	}
+	return strings.Join(parts, " "), nil
 }
 
-func (db *Db) updateOrCreateTableSchema(obj event.EventObject) error {
-	db.lock.Lock()
-	defer db.lock.Unlock()
+func (db *Db) evolution() string {
+	if db.cfg.Evolution == "" {
+		return config.PostgresEvolutionEvolve
+	}
+	return db.cfg.Evolution
+}
 
-	if db.schema != nil {
-		return nil
+func (db *Db) hasNewFields(obj event.EventObject) bool {
+	for k := range obj {
+		if _, ok := db.fields[k]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// evolve updates the table schema along the fields known so far and the event,
+// pending rows are written first as they may not match the new schema
+func (db *Db) evolve(obj event.EventObject) error {
+	fields := make(event.EventObject, len(db.fields)+len(obj))
+	for k, v := range db.fields {
+		fields[k] = v
+	}
+	for k, v := range obj {
+		fields[k] = v
 	}
 
+	db.flush()
+	schema, err := db.updateOrCreateTableSchema(fields)
+	if err != nil {
+		return err
+	}
+	for rows, stmt := range db.stmts {
+		stmt.Close()
+		delete(db.stmts, rows)
+	}
+	db.schema = schema
+	db.fields = fields
+	return nil
+}
+
+func (db *Db) updateOrCreateTableSchema(obj event.EventObject) (*tableSchema, error) {
 	ctx, cancel := context.WithTimeout(db.ctx, db.timeout)
 	defer cancel()
 
+	schemaName, tableName := splitTableName(db.cfg.Table)
 	params := struct {
-		TableName string `db:"table_name"`
+		TableSchema string `db:"table_schema"`
+		TableName   string `db:"table_name"`
 	}{
-		TableName: db.cfg.Table,
+		TableSchema: schemaName,
+		TableName:   tableName,
 	}
 
 	rows, err := db.db.NamedQueryContext(ctx, selectTableColumnsSql, params)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer func() {
 		err := rows.Close()
@@ -161,8 +521,49 @@ func (db *Db) updateOrCreateTableSchema(obj event.EventObject) error {
 		}
 	}()
 
-	sqlColumns := make([]string, 0, 10)
-	converters := make(map[string]Converter, 10)
+	mode := db.cfg.Mode
+	if mode == "" {
+		mode = config.PostgresModeUpsert
+	}
+	keys := make(map[string]bool, len(db.cfg.Keys))
+	for _, key := range db.cfg.Keys {
+		keys[key] = true
+	}
+	for name, column := range db.cfg.Columns {
+		if column.Key {
+			keys[name] = true
+		}
+	}
+	for key := range keys {
+		if _, ok := obj[key]; !ok {
+			return nil, fmt.Errorf("key column %s is missed in event", key)
+		}
+	}
+	if mode == config.PostgresModeScd2 {
+		if _, ok := obj[validFromColumn]; ok {
+			return nil, fmt.Errorf("field %s is reserved in %s mode", validFromColumn, mode)
+		}
+		if _, ok := obj[validToColumn]; ok {
+			return nil, fmt.Errorf("field %s is reserved in %s mode", validToColumn, mode)
+		}
+	}
+	// Configured keys take precedence over both the primary key of the table and the column names
+	isKey := func(name string, v interface{}, isTableKey *bool) bool {
+		switch {
+		case mode == config.PostgresModeAppend:
+			return false
+		case len(keys) > 0:
+			return keys[name]
+		case isTableKey != nil:
+			return *isTableKey
+		}
+		return isKeyColumn(name, v)
+	}
+
+	evolution := db.evolution()
+	columns := make([]tableColumn, 0, len(obj))
+	existing := make(map[string]bool, len(obj))
+	alterSqls := make([]string, 0, len(obj))
 
 	for rows.Next() {
 		row := struct {
@@ -170,115 +571,213 @@ func (db *Db) updateOrCreateTableSchema(obj event.EventObject) error {
 			DataType   string `db:"data_type"`
 			UdtName    string `db:"udt_name"`
 			IsNullable bool   `db:"is_nullable"`
+			IsKey      bool   `db:"is_key"`
 		}{}
 		err := rows.StructScan(&row)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		existing[row.ColumnName] = true
 
 		v, ok := obj[row.ColumnName]
-		if ok {
-			sqlColumnType := sqlColumnType{
-				row.DataType,
-				row.UdtName,
-				row.IsNullable,
+		if !ok {
+			continue
+		}
+		dataType := row.UdtName
+		converter, err := toConverter(dataType, row.IsNullable)
+		if err != nil {
+			return nil, err
+		}
+		_, err = converter(v)
+		if err != nil {
+			wider, ok := "", false
+			if evolution == config.PostgresEvolutionEvolve {
+				wider, ok = widen(dataType, v)
 			}
-			converter, err := toConverterByColumnDef(sqlColumnType, v)
+			if !ok {
+				return nil, fmt.Errorf("field %s doesn't match column type %s: %w", row.ColumnName, row.DataType, err)
+			}
+			name := pq.QuoteIdentifier(row.ColumnName)
+			alterSqls = append(alterSqls, fmt.Sprintf("ALTER COLUMN %s TYPE %s USING %s::%s", name, wider, name, wider))
+			dataType = wider
+			converter, err = toConverter(dataType, row.IsNullable)
 			if err != nil {
-				return err
+				return nil, err
 			}
-			converters[row.ColumnName] = converter
 		}
+		columns = append(columns, tableColumn{
+			Name:      row.ColumnName,
+			DataType:  dataType,
+			Converter: converter,
+			IsKey:     isKey(row.ColumnName, v, &row.IsKey),
+		})
 	}
 
-	noTable := len(converters) == 0
+	noTable := len(existing) == 0
 
+	sqlColumns := make([]string, 0, len(obj))
 	keyColumnNames := make([]string, 0, len(obj))
-	updatedColumnNames := make([]string, len(sqlColumns))
-
-	for k, v := range obj {
-		if _, ok := converters[k]; ok {
+	names := make([]string, 0, len(obj)+len(db.cfg.Columns))
+	for k := range obj {
+		names = append(names, k)
+	}
+	for k := range db.cfg.Columns {
+		if _, ok := obj[k]; !ok {
+			names = append(names, k)
+		}
+	}
+	for _, k := range names {
+		if existing[k] {
 			continue
 		}
-		sqlColumnDef, err := toSqlColumnDefinition(k, v)
+		v, ok := obj[k]
+		if !noTable && evolution != config.PostgresEvolutionEvolve {
+			if ok && evolution == config.PostgresEvolutionReject {
+				return nil, fmt.Errorf("field %s is missed in table %s", k, db.cfg.Table)
+			}
+			continue
+		}
+		// Key of the existing table can't be changed, so new columns are keys only along with the table
+		isKeyColumn := isKey(k, v, nil)
+		var sqlColumnDef *sqlColumnDefinition
+		if column, mapped := db.cfg.Columns[k]; mapped {
+			sqlColumnDef, err = toMappedSqlColumnDefinition(k, column, isKeyColumn)
+		} else {
+			sqlColumnDef, err = toSqlColumnDefinition(k, v, isKeyColumn)
+		}
 		if err != nil {
-			return err
+			return nil, err
 		}
 		sqlColumns = append(sqlColumns, sqlColumnDef.ColumnDefinition)
-		if sqlColumnDef.IsKey {
-			keyColumnNames = append(keyColumnNames, k)
-		} else {
-			updatedColumnNames = append(updatedColumnNames, fmt.Sprintf("%s=EXCLUDED.%s", k, k))
+		// Mapped columns missed in event are created, but left to the column default
+		if !ok {
+			continue
+		}
+		_, err = sqlColumnDef.Converter(v)
+		if err != nil {
+			return nil, fmt.Errorf("field %s doesn't match column type %s: %w", k, sqlColumnDef.DataType, err)
+		}
+		if isKeyColumn && noTable {
+			keyColumnNames = append(keyColumnNames, pq.QuoteIdentifier(k))
 		}
-		converters[k] = sqlColumnDef.Converter
+		columns = append(columns, tableColumn{
+			Name:      k,
+			DataType:  sqlColumnDef.DataType,
+			Converter: sqlColumnDef.Converter,
+			IsKey:     isKeyColumn && (noTable || len(keys) > 0),
+		})
 	}
 
-	if len(sqlColumns) == 0 {
-		return fmt.Errorf("No columns can be add or any table created")
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("No columns can be add or any table created")
 	}
 
+	sort.Strings(sqlColumns)
 	sort.Strings(keyColumnNames)
 
+	if mode == config.PostgresModeScd2 {
+		hasKey := false
+		for _, column := range columns {
+			hasKey = hasKey || column.IsKey
+		}
+		if !hasKey {
+			return nil, fmt.Errorf("key columns are required in %s mode", mode)
+		}
+		if !existing[validFromColumn] {
+			sqlColumns = append(sqlColumns, fmt.Sprintf("%s timestamptz NOT NULL DEFAULT now()", pq.QuoteIdentifier(validFromColumn)))
+		}
+		if !existing[validToColumn] {
+			sqlColumns = append(sqlColumns, fmt.Sprintf("%s timestamptz NULL", pq.QuoteIdentifier(validToColumn)))
+		}
+		// Every version of the row is kept
+		keyColumnNames = append(keyColumnNames, pq.QuoteIdentifier(validFromColumn))
+	}
+
+	partitionBySql := ""
+	if db.cfg.Partition != nil {
+		field := db.cfg.Partition.Field
+		dataType := ""
+		for _, column := range columns {
+			if column.Name == field {
+				dataType = normalizeDataType(column.DataType)
+			}
+		}
+		switch dataType {
+		case "timestamp", "timestamptz", "date":
+		case "":
+			return nil, fmt.Errorf("partition field %s is missed in event", field)
+		default:
+			return nil, fmt.Errorf("partition field %s must be timestamp or date, got %s", field, dataType)
+		}
+		partitionBySql = fmt.Sprintf(" PARTITION BY RANGE (%s)", pq.QuoteIdentifier(field))
+		// Primary key of the partitioned table must include the partition key
+		quotedField := pq.QuoteIdentifier(field)
+		if len(keyColumnNames) > 0 && !containsString(keyColumnNames, quotedField) {
+			keyColumnNames = append(keyColumnNames, quotedField)
+		}
+	}
+
+	table := quoteTable(db.cfg.Table)
 	createOrUpdateTableSql := ""
 	if noTable {
 		// Create table
 		keySql := ""
 		if len(keyColumnNames) > 0 {
-			keySql = fmt.Sprintf(",\nCONSTRAINT pk_%s PRIMARY KEY (%s)", db.cfg.Table, strings.Join(keyColumnNames, ","))
+			keySql = fmt.Sprintf(",\nCONSTRAINT %s PRIMARY KEY (%s)", pq.QuoteIdentifier("pk_"+tableName), strings.Join(keyColumnNames, ","))
+		}
+		createOrUpdateTableSql = fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s%s)%s", table, strings.Join(sqlColumns, ",\n"), keySql, partitionBySql)
+		if schemaName != "" {
+			createOrUpdateTableSql = fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s;\n%s", pq.QuoteIdentifier(schemaName), createOrUpdateTableSql)
 		}
-		createOrUpdateTableSql = fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s%s)", db.cfg.Table, strings.Join(sqlColumns, ",\n"), keySql)
 	} else {
 		// Update table
-		addSqlColumns := make([]string, len(sqlColumns))
-		for i, sqlCol := range sqlColumns {
-			addSqlColumns[i] = fmt.Sprintf("ADD COLUMN %s", sqlCol)
+		for _, sqlCol := range sqlColumns {
+			alterSqls = append(alterSqls, fmt.Sprintf("ADD COLUMN IF NOT EXISTS %s", sqlCol))
+		}
+		if len(alterSqls) > 0 {
+			createOrUpdateTableSql = fmt.Sprintf("ALTER TABLE %s %s", table, strings.Join(alterSqls, ",\n"))
 		}
-		createOrUpdateTableSql = fmt.Sprintf("ALTER TABLE IF EXISTS %s (%s)", db.cfg.Table, strings.Join(addSqlColumns, ",\n"))
 	}
 
-	_, err = db.db.ExecContext(ctx, createOrUpdateTableSql)
-	if err != nil {
-		return err
+	if createOrUpdateTableSql != "" {
+		_, err = db.db.ExecContext(ctx, createOrUpdateTableSql)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	columnsIndices := make(map[string]int, len(sqlColumns))
-	columns := make([]string, len(sqlColumns))
-	for name := range converters {
-		columnsIndices[name] = len(columnsIndices)
-		columns[columnsIndices[name]] = name
+	if db.cfg.Partition != nil {
+		setPartitionKey(columns, db.cfg.Partition.Field, mode)
 	}
-
-	db.schema = &tableSchema{
-		Sql: fmt.Sprintf(
-			"INSERT INTO %s(%s) VALUES(%%s) ON CONFLICT(%s) DO UPDATE SET %s",
-			db.cfg.Table,
-			strings.Join(columns, ","),
-			strings.Join(keyColumnNames, ","),
-			strings.Join(updatedColumnNames, ","),
-		),
-		Converters:     converters,
-		Columns:        columns,
-		ColumnsIndices: columnsIndices,
+	schema := newTableSchema(db.cfg.Table, mode, columns)
+	if db.cfg.Partition != nil {
+		for i, column := range schema.Columns {
+			if column.Name == db.cfg.Partition.Field {
+				schema.PartitionIndex = i
+			}
+		}
 	}
-	return nil
+	return schema, nil
 }
 
-func (db *Db) composeQuery(o event.EventObject) (string, error) {
-	insertValues := make([]string, len(db.schema.ColumnsIndices))
-	for name, index := range db.schema.ColumnsIndices {
-		converter := db.schema.Converters[name]
-		val, ok := o[name]
-		var v string
+func (db *Db) composeRow(o event.EventObject) ([]interface{}, string, error) {
+	values := make([]interface{}, len(db.schema.Columns))
+	for i, column := range db.schema.Columns {
+		val, ok := o[column.Name]
 		if !ok {
-			zap.L().Debug("missed field", zap.String("name", name))
+			zap.L().Debug("missed field", zap.String("name", column.Name))
 		}
-		var err error
-		v, err = converter(val)
+		v, err := column.Converter(val)
 		if err != nil {
-			return "", fmt.Errorf("failed to convert field: %s value: %v failed: %w", name, val, err)
+			return nil, "", fmt.Errorf("failed to convert field: %s value: %v failed: %w", column.Name, val, err)
 		}
-		insertValues[index] = v
+		values[i] = v
+	}
+
+	keyValues := make([]string, len(db.schema.KeyIndices))
+	for i, index := range db.schema.KeyIndices {
+		keyValues[i] = fmt.Sprint(values[index])
 	}
 
-	return fmt.Sprintf(db.schema.Sql, strings.Join(insertValues, ",")), nil
+	return values, strings.Join(keyValues, "\x00"), nil
 }
@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+	"github.com/sibedge-llc/dp-services/eventer/internal/event"
+)
+
+func TestWriteChunks(t *testing.T) {
+	rows := make([][]interface{}, 5)
+	for i := range rows {
+		rows[i] = []interface{}{i}
+	}
+
+	var (
+		written []interface{}
+		failed  []int
+	)
+	done := func(offset int, n int, err error) {
+		if err != nil {
+			for i := offset; i < offset+n; i++ {
+				failed = append(failed, i)
+			}
+		}
+	}
+	unsent, err := writeChunks(context.Background(), rows, 2, func(chunk [][]interface{}) error {
+		if chunk[0][0] == 2 {
+			return errors.New("failed")
+		}
+		for _, row := range chunk {
+			written = append(written, row[0])
+		}
+		return nil
+	}, done)
+	if err == nil || unsent != 2 {
+		t.Errorf("expected 2 unsent rows and error, got %d and %v", unsent, err)
+	}
+	if len(written) != 3 || written[2] != 4 {
+		t.Errorf("rows after the failed chunk are not written: %v", written)
+	}
+	if len(failed) != 2 || failed[0] != 2 || failed[1] != 3 {
+		t.Errorf("every row of the failed chunk must be reported, got %v", failed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	failed = nil
+	unsent, err = writeChunks(ctx, rows, 2, func([][]interface{}) error { return nil }, done)
+	if err == nil || unsent != 5 || len(failed) != 5 {
+		t.Errorf("expected all rows unsent on cancelled context, got %d, %v and %v", unsent, err, failed)
+	}
+}
+
+type testReport struct {
+	delivered int
+	failed    int
+}
+
+func (r *testReport) GetGeneratorId() string { return "test" }
+func (r *testReport) Delivered()             { r.delivered++ }
+func (r *testReport) Failed(err error)       { r.failed++ }
+
+func newTestDb(t *testing.T, mode string, batchSize int) (*Db, *[][][]interface{}) {
+	converter, err := toConverter("int8", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := newDb(context.Background(), 1, &config.PostgresConfig{Table: "t", Mode: mode}, 0, nil, batchSize, 0)
+	db.schema = newTableSchema("t", mode, []tableColumn{
+		{Name: "id", DataType: "bigint", Converter: converter, IsKey: true},
+		{Name: "value", DataType: "bigint", Converter: converter},
+	})
+	db.fields = event.EventObject{"id": nil, "value": nil}
+	var writes [][][]interface{}
+	db.write = func(rows [][]interface{}) error {
+		writes = append(writes, rows)
+		return nil
+	}
+	return db, &writes
+}
+
+func TestDbBatch(t *testing.T) {
+	db, writes := newTestDb(t, config.PostgresModeUpsert, 3)
+	report := &testReport{}
+	for _, obj := range []event.EventObject{
+		{"id": float64(1), "value": float64(1)},
+		{"id": float64(1), "value": float64(2)},
+		{"id": float64(2), "value": float64(3)},
+	} {
+		if err := db.add(obj, report); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*writes) != 0 || report.delivered != 0 {
+		t.Fatalf("batch is written before it's full: %v", *writes)
+	}
+	if err := db.add(event.EventObject{"id": float64(3), "value": float64(4)}, report); err != nil {
+		t.Fatal(err)
+	}
+	// The latest event of the key wins, the replaced one is reported along with it
+	expected := [][][]interface{}{{{int64(1), int64(2)}, {int64(2), int64(3)}, {int64(3), int64(4)}}}
+	if !reflect.DeepEqual(*writes, expected) || report.delivered != 4 {
+		t.Errorf("expected %v written and 4 events reported, got %v and %d", expected, *writes, report.delivered)
+	}
+
+	db, writes = newTestDb(t, config.PostgresModeScd2, 3)
+	for _, value := range []float64{1, 2} {
+		if err := db.add(event.EventObject{"id": float64(1), "value": value}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Flush()
+	// Every version is written by the separate statement
+	expected = [][][]interface{}{{{int64(1), int64(1)}}, {{int64(1), int64(2)}}}
+	if !reflect.DeepEqual(*writes, expected) {
+		t.Errorf("expected versions %v, got %v", expected, *writes)
+	}
+}
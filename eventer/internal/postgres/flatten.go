@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+	"github.com/sibedge-llc/dp-services/eventer/internal/event"
+)
+
+const (
+	defaultSeparator = "_"
+	parentPrefix     = "parent"
+	ordinalColumn    = "ordinal"
+)
+
+// flatten maps nested objects to the columns named by the path,
+// arrays of objects are returned separately to be exploded to the child tables
+func flatten(obj event.EventObject, cfg *config.PostgresFlattenConfig) (event.EventObject, map[string][]interface{}) {
+	if cfg == nil {
+		return obj, nil
+	}
+	flat := make(event.EventObject, len(obj))
+	arrays := make(map[string][]interface{})
+	flattenTo(flat, arrays, "", 0, obj, cfg)
+	return flat, arrays
+}
+
+func flattenTo(flat event.EventObject, arrays map[string][]interface{}, prefix string, depth int, obj map[string]interface{}, cfg *config.PostgresFlattenConfig) {
+	for k, v := range obj {
+		name := k
+		if prefix != "" {
+			name = prefix + separator(cfg) + k
+		}
+		switch t := v.(type) {
+		case map[string]interface{}:
+			// Objects deeper than the limit are kept as json documents
+			if cfg.Depth <= 0 || depth < cfg.Depth {
+				flattenTo(flat, arrays, name, depth+1, t, cfg)
+				continue
+			}
+		case []interface{}:
+			if cfg.Explode && isObjectArray(t) {
+				arrays[name] = t
+				continue
+			}
+		}
+		flat[name] = v
+	}
+}
+
+func separator(cfg *config.PostgresFlattenConfig) string {
+	if cfg.Separator == "" {
+		return defaultSeparator
+	}
+	return cfg.Separator
+}
+
+func isObjectArray(values []interface{}) bool {
+	if len(values) == 0 {
+		return false
+	}
+	for _, v := range values {
+		if _, ok := v.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (db *Db) getChildren() []*Db {
+	db.childLock.Lock()
+	defer db.childLock.Unlock()
+	children := make([]*Db, 0, len(db.children))
+	for _, child := range db.children {
+		children = append(children, child)
+	}
+	return children
+}
+
+// childRows makes rows of the child table linked to the parent row by the parent key and the element position
+func (db *Db) childRows(path string, parent event.EventObject, elems []interface{}) (*Db, []event.EventObject, error) {
+	sep := separator(db.cfg.Flatten)
+	db.batchLock.Lock()
+	keys := make([]string, len(db.schema.KeyIndices))
+	for i, index := range db.schema.KeyIndices {
+		keys[i] = db.schema.Columns[index].Name
+	}
+	db.batchLock.Unlock()
+	if len(keys) == 0 {
+		return nil, nil, fmt.Errorf("array %s can't be exploded as table %s has no keys", path, db.cfg.Table)
+	}
+
+	child := db.child(path, keys)
+	rows := make([]event.EventObject, len(elems))
+	for i, elem := range elems {
+		obj := elem.(map[string]interface{})
+		row := make(event.EventObject, len(obj)+len(keys)+1)
+		for k, v := range obj {
+			row[k] = v
+		}
+		for _, key := range keys {
+			row[parentPrefix+sep+key] = parent[key]
+		}
+		row[ordinalColumn] = float64(i)
+		rows[i] = row
+	}
+	return child, rows, nil
+}
+
+func (db *Db) child(path string, keys []string) *Db {
+	db.childLock.Lock()
+	defer db.childLock.Unlock()
+	child, ok := db.children[path]
+	if ok {
+		return child
+	}
+
+	sep := separator(db.cfg.Flatten)
+	cfg := *db.cfg
+	cfg.Table = db.cfg.Table + sep + path
+	cfg.Mode = config.PostgresModeUpsert
+	cfg.Keys = make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		cfg.Keys = append(cfg.Keys, parentPrefix+sep+key)
+	}
+	cfg.Keys = append(cfg.Keys, ordinalColumn)
+	cfg.Columns = nil
+	cfg.Partition = nil
+	child = newDb(db.ctx, db.id, &cfg, db.timeout, db.db, db.batchSize, db.flushInterval)
+	child.retry = db.retry
+	db.children[path] = child
+	return child
+}
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+	"github.com/sibedge-llc/dp-services/eventer/internal/event"
+)
+
+func TestFlatten(t *testing.T) {
+	obj := event.EventObject{
+		"id": float64(1),
+		"event": map[string]interface{}{
+			"type": "click",
+			"data": map[string]interface{}{
+				"click": map[string]interface{}{"x": float64(10)},
+			},
+		},
+		"tags":  []interface{}{"a", "b"},
+		"items": []interface{}{map[string]interface{}{"sku": "x"}},
+	}
+
+	flat, arrays := flatten(obj, &config.PostgresFlattenConfig{Explode: true})
+	expected := event.EventObject{
+		"id":                 float64(1),
+		"event_type":         "click",
+		"event_data_click_x": float64(10),
+		"tags":               []interface{}{"a", "b"},
+	}
+	if !reflect.DeepEqual(flat, expected) {
+		t.Errorf("expected %v, got %v", expected, flat)
+	}
+	if len(arrays) != 1 || len(arrays["items"]) != 1 {
+		t.Errorf("expected items to be exploded, got %v", arrays)
+	}
+
+	flat, arrays = flatten(obj, &config.PostgresFlattenConfig{Separator: ".", Depth: 1})
+	expected = event.EventObject{
+		"id":         float64(1),
+		"event.type": "click",
+		"event.data": map[string]interface{}{
+			"click": map[string]interface{}{"x": float64(10)},
+		},
+		"tags":  []interface{}{"a", "b"},
+		"items": []interface{}{map[string]interface{}{"sku": "x"}},
+	}
+	if !reflect.DeepEqual(flat, expected) {
+		t.Errorf("expected %v, got %v", expected, flat)
+	}
+	if len(arrays) != 0 {
+		t.Errorf("expected no exploded arrays, got %v", arrays)
+	}
+
+	flat, _ = flatten(obj, nil)
+	if !reflect.DeepEqual(flat, obj) {
+		t.Errorf("expected event intact without flattening, got %v", flat)
+	}
+}
@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+)
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// setPartitionKey marks the partition column as the key when the table has keys, the primary key of the partitioned table
+// includes the partition column, so the conflict target has to include it as well
+func setPartitionKey(columns []tableColumn, field string, mode string) {
+	if mode == config.PostgresModeAppend || mode == config.PostgresModeScd2 {
+		return
+	}
+	hasKey := false
+	for _, column := range columns {
+		hasKey = hasKey || column.IsKey
+	}
+	if !hasKey {
+		return
+	}
+	for i := range columns {
+		if columns[i].Name == field {
+			columns[i].IsKey = true
+		}
+	}
+}
+
+// partitionRange returns the suffix of the partition name and the range of the partition containing the value
+func partitionRange(interval string, v interface{}) (string, time.Time, time.Time, error) {
+	var t time.Time
+	switch value := v.(type) {
+	case time.Time:
+		t = value.UTC()
+	case string:
+		var err error
+		for _, layout := range timeLayouts {
+			t, err = time.Parse(layout, value)
+			if err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return "", t, t, fmt.Errorf("failed to parse partition value %s", value)
+		}
+		t = t.UTC()
+	default:
+		return "", t, t, fmt.Errorf("partition value must be time, got %T", v)
+	}
+
+	if interval == config.PostgresPartitionMonthly {
+		from := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return from.Format("200601"), from, from.AddDate(0, 1, 0), nil
+	}
+	from := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return from.Format("20060102"), from, from.AddDate(0, 0, 1), nil
+}
+
+func partitionSql(table string, suffix string, from time.Time, to time.Time) string {
+	schema, name := splitTableName(table)
+	partition := name + "_" + suffix
+	if schema != "" {
+		partition = schema + "." + partition
+	}
+	const layout = "2006-01-02 15:04:05Z07:00"
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM (%s) TO (%s)",
+		quoteTable(partition), quoteTable(table), pq.QuoteLiteral(from.Format(layout)), pq.QuoteLiteral(to.Format(layout)))
+}
+
+// ensurePartition creates the partition of the value unless it's created already
+func (db *Db) ensurePartition(v interface{}) error {
+	if v == nil {
+		return fmt.Errorf("partition field %s is null", db.cfg.Partition.Field)
+	}
+	suffix, from, to, err := partitionRange(db.cfg.Partition.Interval, v)
+	if err != nil {
+		return err
+	}
+	if db.partitions[suffix] {
+		return nil
+	}
+
+	err = db.retry.Do(db.ctx, "postgres partition", func() error {
+		ctx, cancel := context.WithTimeout(db.ctx, db.timeout)
+		defer cancel()
+		_, err := db.db.ExecContext(ctx, partitionSql(db.cfg.Table, suffix, from, to))
+		return err
+	}, zap.String("table", db.cfg.Table))
+	if err != nil {
+		return fmt.Errorf("failed to create partition %s of table %s: %w", suffix, db.cfg.Table, err)
+	}
+	db.partitions[suffix] = true
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS %s (
+   instance_id text NOT NULL,
+   id text NOT NULL,
+   registration jsonb NOT NULL,
+   updated_at timestamp NOT NULL DEFAULT now(),
+   CONSTRAINT %s PRIMARY KEY (instance_id, id)
+)
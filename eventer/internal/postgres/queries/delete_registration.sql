@@ -0,0 +1,4 @@
+DELETE FROM 
+   %s
+WHERE 
+   instance_id = $1 AND id = $2
@@ -0,0 +1,8 @@
+SELECT 
+   registration
+FROM 
+   %s
+WHERE 
+   instance_id = $1
+ORDER BY 
+   id
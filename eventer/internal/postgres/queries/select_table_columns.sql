@@ -1,9 +1,23 @@
 SELECT 
-   column_name,
-   lower(data_type) as data_type,
-   lower(udt_name) as udt_name,
-   is_nullable
+   c.column_name,
+   lower(c.data_type) as data_type,
+   lower(c.udt_name) as udt_name,
+   c.is_nullable = 'YES' as is_nullable,
+   EXISTS (
+      SELECT 1
+      FROM 
+         information_schema.table_constraints tc
+         JOIN information_schema.key_column_usage kcu
+            ON kcu.constraint_schema = tc.constraint_schema
+            AND kcu.constraint_name = tc.constraint_name
+      WHERE 
+         tc.constraint_type = 'PRIMARY KEY'
+         AND tc.table_schema = c.table_schema
+         AND tc.table_name = c.table_name
+         AND kcu.column_name = c.column_name
+   ) as is_key
 FROM 
-   information_schema.columns
+   information_schema.columns c
 WHERE 
-   table_name = :table_name
+   c.table_name = :table_name
+   AND c.table_schema = COALESCE(NULLIF(:table_schema, ''), current_schema())
@@ -0,0 +1,5 @@
+INSERT INTO %s (instance_id, id, registration, updated_at)
+VALUES ($1, $2, $3, now())
+ON CONFLICT (instance_id, id) DO UPDATE SET 
+   registration = EXCLUDED.registration,
+   updated_at = EXCLUDED.updated_at
@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+	"github.com/sibedge-llc/dp-services/eventer/internal/generator"
+)
+
+var (
+	//go:embed queries/create_registry_table.sql
+	createRegistryTableSql string
+	//go:embed queries/select_registrations.sql
+	selectRegistrationsSql string
+	//go:embed queries/upsert_registration.sql
+	upsertRegistrationSql string
+	//go:embed queries/delete_registration.sql
+	deleteRegistrationSql string
+)
+
+type Registry struct {
+	ctx        context.Context
+	db         *sqlx.DB
+	timeout    time.Duration
+	instanceId string
+	table      string
+}
+
+func NewRegistry(ctx context.Context, cfg *config.PostgresConfig, instanceId string, table string, timeout time.Duration) (*Registry, error) {
+	if table == "" {
+		return nil, errors.New("registry table name is empty or not provided")
+	}
+
+	dataSource, err := getDataSource(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sqlx.ConnectContext(ctx, "postgres", dataSource)
+	if err != nil {
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
+
+	r := &Registry{
+		ctx:        ctx,
+		db:         db,
+		timeout:    timeout,
+		instanceId: instanceId,
+		table:      table,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	_, err = db.ExecContext(ctx, fmt.Sprintf(createRegistryTableSql, pq.QuoteIdentifier(table), pq.QuoteIdentifier("pk_"+table)))
+	if err != nil {
+		r.Close()
+		return nil, fmt.Errorf("failed to create registry table: %w", err)
+	}
+
+	return r, nil
+}
+
+func (r *Registry) Load() ([]generator.Registration, error) {
+	ctx, cancel := context.WithTimeout(r.ctx, r.timeout)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(selectRegistrationsSql, pq.QuoteIdentifier(r.table)), r.instanceId)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			zap.L().Error("close rows error", zap.Error(err))
+		}
+	}()
+
+	registrations := make([]generator.Registration, 0, 10)
+	for rows.Next() {
+		var data []byte
+		err := rows.Scan(&data)
+		if err != nil {
+			return nil, err
+		}
+		var registration generator.Registration
+		err = json.Unmarshal(data, &registration)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse registration: %w", err)
+		}
+		registrations = append(registrations, registration)
+	}
+	return registrations, rows.Err()
+}
+
+func (r *Registry) Save(registrations ...generator.Registration) error {
+	ctx, cancel := context.WithTimeout(r.ctx, r.timeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(upsertRegistrationSql, pq.QuoteIdentifier(r.table))
+	for _, registration := range registrations {
+		data, err := json.Marshal(registration)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, query, r.instanceId, strconv.FormatUint(registration.Id, 10), string(data))
+		if err != nil {
+			return fmt.Errorf("failed to save registration %d: %w", registration.Id, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *Registry) Delete(id uint64) error {
+	ctx, cancel := context.WithTimeout(r.ctx, r.timeout)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, fmt.Sprintf(deleteRegistrationSql, pq.QuoteIdentifier(r.table)), r.instanceId, strconv.FormatUint(id, 10))
+	return err
+}
+
+func (r *Registry) Close() {
+	err := r.db.Close()
+	if err != nil {
+		zap.L().Error("failed to close postgres registry", zap.Error(err))
+	}
+}
@@ -0,0 +1,25 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// isRetryable tells whether the error is caused by connection or server state rather than the data
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "08", "40", "53", "57":
+			// Connection exception, transaction rollback, insufficient resources, operator intervention
+			return true
+		}
+		return false
+	}
+	return true
+}
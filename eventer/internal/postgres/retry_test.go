@@ -0,0 +1,27 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		err       error
+		retryable bool
+	}{
+		{&pq.Error{Code: "08006"}, true},
+		{&pq.Error{Code: "40P01"}, true},
+		{&pq.Error{Code: "23505"}, false},
+		{errors.New("connection refused"), true},
+		{context.Canceled, false},
+	}
+	for _, c := range cases {
+		if isRetryable(c.err) != c.retryable {
+			t.Errorf("unexpected retryable %v of error %v", !c.retryable, c.err)
+		}
+	}
+}
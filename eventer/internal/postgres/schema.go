@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+)
+
+const (
+	maxBindParams = 65535
+
+	validFromColumn = "valid_from"
+	validToColumn   = "valid_to"
+)
+
+type tableColumn struct {
+	Name      string
+	DataType  string
+	Converter Converter
+	IsKey     bool
+}
+
+// splitTableName splits the schema qualified table name, schema is empty for the bare name
+func splitTableName(table string) (string, string) {
+	if i := strings.Index(table, "."); i > 0 {
+		return table[:i], table[i+1:]
+	}
+	return "", table
+}
+
+func quoteTable(table string) string {
+	schema, name := splitTableName(table)
+	if schema == "" {
+		return pq.QuoteIdentifier(name)
+	}
+	return pq.QuoteIdentifier(schema) + "." + pq.QuoteIdentifier(name)
+}
+
+type tableSchema struct {
+	Mode           string
+	Columns        []tableColumn
+	KeyIndices     []int
+	PartitionIndex int
+	prefix         string
+	suffix         string
+	casts          []string
+}
+
+func newTableSchema(table string, mode string, columns []tableColumn) *tableSchema {
+	sort.Slice(columns, func(i, j int) bool {
+		return columns[i].Name < columns[j].Name
+	})
+
+	names := make([]string, len(columns))
+	keys := make([]string, 0, len(columns))
+	keyIndices := make([]int, 0, len(columns))
+	updates := make([]string, 0, len(columns))
+	for i, column := range columns {
+		name := pq.QuoteIdentifier(column.Name)
+		names[i] = name
+		if column.IsKey {
+			keys = append(keys, name)
+			keyIndices = append(keyIndices, i)
+		} else {
+			updates = append(updates, fmt.Sprintf("%s=EXCLUDED.%s", name, name))
+		}
+	}
+
+	s := &tableSchema{
+		Mode:           mode,
+		Columns:        columns,
+		KeyIndices:     keyIndices,
+		PartitionIndex: -1,
+	}
+	quotedTable := quoteTable(table)
+	columnList := strings.Join(names, ",")
+	s.prefix = fmt.Sprintf("INSERT INTO %s (%s) VALUES ", quotedTable, columnList)
+
+	switch mode {
+	case config.PostgresModeAppend:
+	case config.PostgresModeIgnore:
+		if len(keys) > 0 {
+			s.suffix = fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", strings.Join(keys, ","))
+		} else {
+			s.suffix = " ON CONFLICT DO NOTHING"
+		}
+	case config.PostgresModeScd2:
+		// Current versions of the rows are closed and the new versions are inserted by the same statement,
+		// values are typed explicitly as they are not inserted directly
+		matches := make([]string, len(keys))
+		for i, key := range keys {
+			matches[i] = fmt.Sprintf("cur.%s = new_rows.%s", key, key)
+		}
+		validFrom := pq.QuoteIdentifier(validFromColumn)
+		validTo := pq.QuoteIdentifier(validToColumn)
+		s.prefix = fmt.Sprintf("WITH new_rows (%s) AS (VALUES ", columnList)
+		s.suffix = fmt.Sprintf("), closed AS (UPDATE %s AS cur SET %s = now() FROM new_rows WHERE %s AND cur.%s IS NULL) "+
+			"INSERT INTO %s (%s,%s) SELECT %s,now() FROM new_rows",
+			quotedTable, validTo, strings.Join(matches, " AND "), validTo,
+			quotedTable, columnList, validFrom, columnList)
+		s.casts = make([]string, len(columns))
+		for i, column := range columns {
+			s.casts[i] = "::" + column.DataType
+		}
+	default:
+		if len(keys) == 0 {
+			break
+		}
+		if len(updates) > 0 {
+			s.suffix = fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(keys, ","), strings.Join(updates, ","))
+		} else {
+			s.suffix = fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", strings.Join(keys, ","))
+		}
+	}
+	return s
+}
+
+// Sql returns the statement writing the number of rows by bind parameters
+func (s *tableSchema) Sql(rows int) string {
+	var b strings.Builder
+	b.WriteString(s.prefix)
+	n := 1
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('(')
+		for j := range s.Columns {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, "$%d", n)
+			if s.casts != nil {
+				b.WriteString(s.casts[j])
+			}
+			n++
+		}
+		b.WriteByte(')')
+	}
+	b.WriteString(s.suffix)
+	return b.String()
+}
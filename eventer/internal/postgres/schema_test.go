@@ -0,0 +1,224 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+)
+
+func TestTableSchemaSql(t *testing.T) {
+	schema := newTableSchema("Events", config.PostgresModeUpsert, []tableColumn{
+		{Name: "value"},
+		{Name: "id", IsKey: true},
+		{Name: "Name"},
+	})
+	expected := `INSERT INTO "Events" ("Name","id","value") VALUES ($1,$2,$3),($4,$5,$6)` +
+		` ON CONFLICT ("id") DO UPDATE SET "Name"=EXCLUDED."Name","value"=EXCLUDED."value"`
+	if sql := schema.Sql(2); sql != expected {
+		t.Errorf("expected %s, got %s", expected, sql)
+	}
+	if !reflect.DeepEqual(schema.KeyIndices, []int{1}) {
+		t.Errorf("unexpected key indices %v", schema.KeyIndices)
+	}
+
+	schema = newTableSchema("t", config.PostgresModeUpsert, []tableColumn{{Name: "a"}})
+	if sql := schema.Sql(1); sql != `INSERT INTO "t" ("a") VALUES ($1)` {
+		t.Errorf("unexpected statement without keys %s", sql)
+	}
+}
+
+func TestTableSchemaModes(t *testing.T) {
+	columns := []tableColumn{
+		{Name: "id", DataType: "integer", IsKey: true},
+		{Name: "value", DataType: "text"},
+	}
+	tests := []struct {
+		mode     string
+		expected string
+	}{
+		{config.PostgresModeAppend, `INSERT INTO "t" ("id","value") VALUES ($1,$2)`},
+		{config.PostgresModeIgnore, `INSERT INTO "t" ("id","value") VALUES ($1,$2) ON CONFLICT ("id") DO NOTHING`},
+		{config.PostgresModeScd2, `WITH new_rows ("id","value") AS (VALUES ($1::integer,$2::text)), ` +
+			`closed AS (UPDATE "t" AS cur SET "valid_to" = now() FROM new_rows WHERE cur."id" = new_rows."id" AND cur."valid_to" IS NULL) ` +
+			`INSERT INTO "t" ("id","value","valid_from") SELECT "id","value",now() FROM new_rows`},
+	}
+	for _, test := range tests {
+		schema := newTableSchema("t", test.mode, append([]tableColumn(nil), columns...))
+		if sql := schema.Sql(1); sql != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.mode, test.expected, sql)
+		}
+	}
+}
+
+func TestConverter(t *testing.T) {
+	tests := []struct {
+		dataType   string
+		isNullable bool
+		in         interface{}
+		out        interface{}
+	}{
+		{"int4", false, float64(42), int64(42)},
+		{"bigint", false, nil, int64(0)},
+		{"integer", true, nil, nil},
+		{"numeric(10,2)", true, "12.345", "12.345"},
+		{"bool", true, "true", true},
+		{"text", true, float64(1.5), "1.5"},
+		{"jsonb", true, map[string]interface{}{"a": float64(1)}, `{"a":1}`},
+		{"timestamp", true, float64(1), time.Unix(1, 0).UTC()},
+		{"text[]", true, []interface{}{"a", "b"}, pq.GenericArray{A: []interface{}{"a", "b"}}},
+		{"_int4", true, []interface{}{float64(1)}, pq.GenericArray{A: []interface{}{int64(1)}}},
+	}
+	for _, test := range tests {
+		converter, err := toConverter(test.dataType, test.isNullable)
+		if err != nil {
+			t.Fatalf("make converter for %s failed: %v", test.dataType, err)
+		}
+		out, err := converter(test.in)
+		if err != nil {
+			t.Fatalf("convert %v to %s failed: %v", test.in, test.dataType, err)
+		}
+		if !reflect.DeepEqual(out, test.out) {
+			t.Errorf("expected %#v for %s, got %#v", test.out, test.dataType, out)
+		}
+	}
+
+	converter, _ := toConverter("uuid", false)
+	if out, _ := converter(nil); out != "" {
+		t.Errorf("expected empty default for not nullable column, got %#v", out)
+	}
+
+	converter, _ = toConverter("integer", true)
+	if _, err := converter(float64(1.5)); err == nil {
+		t.Error("expected error for non integer value")
+	}
+}
+
+func TestMappedColumnDefinition(t *testing.T) {
+	nullable := false
+	tests := []struct {
+		column   config.PostgresColumnConfig
+		isKey    bool
+		expected string
+	}{
+		{config.PostgresColumnConfig{Type: "UUID"}, true, `"c" uuid NOT NULL`},
+		{config.PostgresColumnConfig{Type: "numeric(12, 2)"}, false, `"c" numeric(12, 2) NULL`},
+		{config.PostgresColumnConfig{Type: "timestamptz", Nullable: &nullable, Default: "now()"}, false, `"c" timestamptz NOT NULL DEFAULT now()`},
+		{config.PostgresColumnConfig{Type: "inet[]"}, false, `"c" inet[] NULL`},
+		{config.PostgresColumnConfig{Type: "numeric", Default: "-1.5"}, false, `"c" numeric NULL DEFAULT -1.5`},
+		{config.PostgresColumnConfig{Type: "text", Default: "new"}, false, `"c" text NULL DEFAULT 'new'`},
+		{config.PostgresColumnConfig{Type: "text", Default: "now(); DROP TABLE t"}, false, `"c" text NULL DEFAULT 'now(); DROP TABLE t'`},
+		{config.PostgresColumnConfig{Type: "text", Default: "'x') NULL; DROP TABLE t; --"}, false, `"c" text NULL DEFAULT '''x'') NULL; DROP TABLE t; --'`},
+	}
+	for _, test := range tests {
+		def, err := toMappedSqlColumnDefinition("c", test.column, test.isKey)
+		if err != nil {
+			t.Fatalf("make definition for %s failed: %v", test.column.Type, err)
+		}
+		if def.ColumnDefinition != test.expected {
+			t.Errorf("expected %s, got %s", test.expected, def.ColumnDefinition)
+		}
+	}
+
+	for _, dataType := range []string{"integer; DROP TABLE t", "geometry"} {
+		_, err := toMappedSqlColumnDefinition("c", config.PostgresColumnConfig{Type: dataType}, false)
+		if err == nil {
+			t.Errorf("expected error for type %s", dataType)
+		}
+	}
+}
+
+func TestWiden(t *testing.T) {
+	tests := []struct {
+		dataType string
+		value    interface{}
+		expected string
+	}{
+		{"int4", float64(1 << 40), "int8"},
+		{"integer", float64(1.5), "numeric"},
+		{"int2", float64(1 << 20), "int4"},
+		{"int8", float64(1e20), "numeric"},
+		{"_int4", []interface{}{float64(1 << 40)}, "_int8"},
+		{"float4", float64(1e300), "float8"},
+	}
+	for _, test := range tests {
+		wider, ok := widen(test.dataType, test.value)
+		if !ok || wider != test.expected {
+			t.Errorf("expected %s widened to %s, got %s", test.dataType, test.expected, wider)
+		}
+	}
+
+	if _, ok := widen("bool", "abc"); ok {
+		t.Error("expected bool not to be widened")
+	}
+}
+
+func TestQuoteTable(t *testing.T) {
+	if table := quoteTable("staging.Events"); table != `"staging"."Events"` {
+		t.Errorf("unexpected schema qualified table %s", table)
+	}
+	if table := quoteTable("events"); table != `"events"` {
+		t.Errorf("unexpected table %s", table)
+	}
+}
+
+func TestPartition(t *testing.T) {
+	tests := []struct {
+		interval string
+		value    interface{}
+		expected string
+	}{
+		{config.PostgresPartitionDaily, time.Date(2021, 12, 31, 23, 0, 0, 0, time.UTC),
+			`CREATE TABLE IF NOT EXISTS "staging"."events_20211231" PARTITION OF "staging"."events" FOR VALUES FROM ('2021-12-31 00:00:00Z') TO ('2022-01-01 00:00:00Z')`},
+		{config.PostgresPartitionMonthly, "2021-12-05 10:00:00",
+			`CREATE TABLE IF NOT EXISTS "staging"."events_202112" PARTITION OF "staging"."events" FOR VALUES FROM ('2021-12-01 00:00:00Z') TO ('2022-01-01 00:00:00Z')`},
+	}
+	for _, test := range tests {
+		suffix, from, to, err := partitionRange(test.interval, test.value)
+		if err != nil {
+			t.Fatalf("get partition of %v failed: %v", test.value, err)
+		}
+		if sql := partitionSql("staging.events", suffix, from, to); sql != test.expected {
+			t.Errorf("expected %s, got %s", test.expected, sql)
+		}
+	}
+
+	_, _, _, err := partitionRange(config.PostgresPartitionDaily, "yesterday")
+	if err == nil {
+		t.Error("expected error for invalid time")
+	}
+}
+
+func TestPartitionKey(t *testing.T) {
+	columns := func() []tableColumn {
+		return []tableColumn{
+			{Name: "id", DataType: "bigint", IsKey: true},
+			{Name: "ts", DataType: "timestamptz"},
+			{Name: "value", DataType: "text"},
+		}
+	}
+	tests := []struct {
+		mode     string
+		expected string
+	}{
+		{config.PostgresModeUpsert, `INSERT INTO "t" ("id","ts","value") VALUES ($1,$2,$3) ON CONFLICT ("id","ts") DO UPDATE SET "value"=EXCLUDED."value"`},
+		{config.PostgresModeIgnore, `INSERT INTO "t" ("id","ts","value") VALUES ($1,$2,$3) ON CONFLICT ("id","ts") DO NOTHING`},
+	}
+	for _, test := range tests {
+		c := columns()
+		setPartitionKey(c, "ts", test.mode)
+		schema := newTableSchema("t", test.mode, c)
+		if sql := schema.Sql(1); sql != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.mode, test.expected, sql)
+		}
+	}
+
+	c := []tableColumn{{Name: "ts"}, {Name: "value"}}
+	setPartitionKey(c, "ts", config.PostgresModeUpsert)
+	if c[0].IsKey {
+		t.Error("partition column is the key of the table without keys")
+	}
+}
@@ -48,23 +48,8 @@ func (s *Service) Register(cfg *config.PostgresConfig, timeout time.Duration) (*
 	db, ok := s.dbs[id]
 	if !ok {
 		var err error
-		if cfg.Host == "" {
-			cfg.Host = s.Default.GetConfig().Host
-		}
-		if cfg.Db == "" {
-			cfg.Db = s.Default.GetConfig().Db
-		}
-		if cfg.Port == 0 {
-			cfg.Port = s.Default.GetConfig().Port
-		}
-		if cfg.User == "" {
-			cfg.User = s.Default.GetConfig().User
-		}
-		if cfg.Password == "" {
-			cfg.Password = s.Default.GetConfig().Password
-		}
-		if cfg.Table == "" {
-			cfg.Table = s.Default.GetConfig().Table
+		if s.Default != nil {
+			cfg = inheritConfig(cfg, s.Default.GetConfig())
 		}
 		db, err = NewDb(s.ctx, id, cfg, timeout)
 		if err != nil {
@@ -74,3 +59,56 @@ func (s *Service) Register(cfg *config.PostgresConfig, timeout time.Duration) (*
 	}
 	return db, nil
 }
+
+// inheritConfig applies the connection settings of the default config to the copy of the config,
+// keys, columns, flatten, partition, mode and evolution describe the table so they are not inherited
+func inheritConfig(cfg, defaultCfg *config.PostgresConfig) *config.PostgresConfig {
+	c := *cfg
+	cfg = &c
+	if cfg.Host == "" {
+		cfg.Host = defaultCfg.Host
+	}
+	if cfg.Db == "" {
+		cfg.Db = defaultCfg.Db
+	}
+	if cfg.Port == 0 {
+		cfg.Port = defaultCfg.Port
+	}
+	if cfg.User == "" {
+		cfg.User = defaultCfg.User
+	}
+	if cfg.Password == "" {
+		cfg.Password = defaultCfg.Password
+	}
+	if cfg.Table == "" {
+		cfg.Table = defaultCfg.Table
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = defaultCfg.BatchSize
+	}
+	if cfg.FlushInterval == "" {
+		cfg.FlushInterval = defaultCfg.FlushInterval
+	}
+	if cfg.MaxOpenConns == 0 {
+		cfg.MaxOpenConns = defaultCfg.MaxOpenConns
+	}
+	if cfg.MaxIdleConns == 0 {
+		cfg.MaxIdleConns = defaultCfg.MaxIdleConns
+	}
+	if cfg.ConnMaxLifetime == "" {
+		cfg.ConnMaxLifetime = defaultCfg.ConnMaxLifetime
+	}
+	if cfg.StatementTimeout == "" {
+		cfg.StatementTimeout = defaultCfg.StatementTimeout
+	}
+	if cfg.RetryAttempts == 0 {
+		cfg.RetryAttempts = defaultCfg.RetryAttempts
+	}
+	if cfg.RetryBackoff == "" {
+		cfg.RetryBackoff = defaultCfg.RetryBackoff
+	}
+	if cfg.RetryMaxBackoff == "" {
+		cfg.RetryMaxBackoff = defaultCfg.RetryMaxBackoff
+	}
+	return cfg
+}
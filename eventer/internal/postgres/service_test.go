@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+)
+
+func TestInheritConfig(t *testing.T) {
+	defaultCfg := &config.PostgresConfig{
+		Host:          "db",
+		Port:          5432,
+		User:          "user",
+		Password:      "secret",
+		Table:         "events",
+		BatchSize:     100,
+		Mode:          config.PostgresModeScd2,
+		Keys:          []string{"id"},
+		Columns:       map[string]config.PostgresColumnConfig{"id": {Type: "bigint"}},
+		Flatten:       &config.PostgresFlattenConfig{Separator: "_"},
+		Evolution:     config.PostgresEvolutionReject,
+		Partition:     &config.PostgresPartitionConfig{Field: "ts", Interval: config.PostgresPartitionDaily},
+		RetryAttempts: 3,
+	}
+
+	cfg := &config.PostgresConfig{Table: "boo"}
+	inherited := inheritConfig(cfg, defaultCfg)
+	if inherited.Host != "db" || inherited.Port != 5432 || inherited.User != "user" || inherited.Password != "secret" ||
+		inherited.Table != "boo" || inherited.BatchSize != 100 || inherited.RetryAttempts != 3 {
+		t.Errorf("connection settings are not inherited %+v", inherited)
+	}
+	if inherited.Mode != "" || inherited.Keys != nil || inherited.Columns != nil || inherited.Flatten != nil ||
+		inherited.Evolution != "" || inherited.Partition != nil {
+		t.Errorf("table settings are inherited %+v", inherited)
+	}
+	if cfg.Host != "" {
+		t.Error("supplied config is changed")
+	}
+}
@@ -1,26 +1,114 @@
 package service
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/gorilla/mux"
+
 	"github.com/sibedge-llc/dp-services/eventer/internal/event"
 	"github.com/sibedge-llc/dp-services/eventer/internal/generator"
+	"github.com/sibedge-llc/dp-services/eventer/internal/postgres"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+
+	defaultPreviewCount = 1
+	maxPreviewCount     = 100
+	previewName         = "preview"
 )
 
 type GeneratorStatus struct {
-	Id     string `json:"id"`
-	Count  int64  `json:"count"`
-	Active bool   `json:"active"`
+	Id                string                `json:"id"`
+	DestinationType   string                `json:"destination_type"`
+	Target            string                `json:"target"`
+	EventId           string                `json:"event_id"`
+	Dataset           string                `json:"dataset"`
+	Interval          string                `json:"interval,omitempty"`
+	Rate              *event.RateDesc       `json:"rate,omitempty"`
+	TargetRate        float64               `json:"target_rate"`
+	AchievedRate      float64               `json:"achieved_rate"`
+	Throughput        *event.ThroughputDesc `json:"throughput,omitempty"`
+	Count             int64                 `json:"count"`
+	State             generator.State       `json:"state"`
+	Failures          int64                 `json:"failures"`
+	Consecutive       int64                 `json:"consecutive_failures"`
+	LastError         string                `json:"last_error,omitempty"`
+	Delivered         int64                 `json:"delivered,omitempty"`
+	DeliveryFailures  int64                 `json:"delivery_failures,omitempty"`
+	LastDeliveryError string                `json:"last_delivery_error,omitempty"`
 }
 
 type AddGeneratorResponse struct {
 	Generators []GeneratorStatus `json:"generators"`
 }
 
+type ListGeneratorsResponse struct {
+	Generators []GeneratorStatus `json:"generators"`
+	Cursor     string            `json:"cursor,omitempty"`
+}
+
+// PendingGenerator is the generator saved without destination credentials, it's restored once it's added again along with them
+type PendingGenerator struct {
+	Id              string `json:"id"`
+	DestinationType string `json:"destination_type"`
+	EventId         string `json:"event_id"`
+	Count           int64  `json:"count"`
+	Paused          bool   `json:"paused,omitempty"`
+}
+
+type PendingGeneratorsResponse struct {
+	Generators []PendingGenerator `json:"generators"`
+}
+
+type PreviewRequest struct {
+	event.EventDesc
+	N int `json:"n"`
+}
+
+type PreviewResponse struct {
+	Events  []json.RawMessage           `json:"events"`
+	Columns []postgres.ColumnDefinition `json:"columns,omitempty"`
+	Error   *event.SchemaError          `json:"error,omitempty"`
+}
+
+func toGeneratorStatus(generator *generator.Generator) GeneratorStatus {
+	count, _ := generator.GetStatus()
+	failures, consecutive, lastError := generator.GetFailures()
+	delivered, deliveryFailures, lastDeliveryError := generator.GetDeliveries()
+	destination := generator.GetDestination()
+	var interval string
+	if generator.GetRate() == nil {
+		interval = generator.GetInterval().String()
+	}
+	return GeneratorStatus{
+		Id:                generator.GetId(),
+		DestinationType:   destination.GetType(),
+		Target:            destination.GetTarget(),
+		EventId:           generator.GetEventId(),
+		Dataset:           generator.GetDataset(),
+		Interval:          interval,
+		Rate:              generator.GetRate(),
+		TargetRate:        generator.GetTargetRate(),
+		AchievedRate:      generator.GetAchievedRate(),
+		Throughput:        generator.GetThroughput(),
+		Count:             count,
+		State:             generator.GetState(),
+		Failures:          failures,
+		Consecutive:       consecutive,
+		LastError:         lastError,
+		Delivered:         delivered,
+		DeliveryFailures:  deliveryFailures,
+		LastDeliveryError: lastDeliveryError,
+	}
+}
+
 func (s *service) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
 	WriteObject(w, map[string]interface{}{
 		"result": http.StatusText(http.StatusOK),
@@ -54,32 +142,23 @@ func (s *service) handleGeneratorAdd(w http.ResponseWriter, r *http.Request) {
 		eventDescs[eventDesc.Id] = eventDesc
 	}
 
+	destinationDescs := make(map[string]event.DestinationDesc, len(request.Destinations))
 	destinations := make(map[string]generator.Destinaton, len(request.Destinations))
 	for _, destination := range request.Destinations {
 		if destination.Id == "" {
 			WriteError(w, http.StatusBadRequest, fmt.Sprintf("destination id is empty or not defined"))
 			return
 		}
-		var generatorDestination generator.Destinaton
-		switch destination.Type {
-		case event.DestinationTypeKafka:
-			producer, err := s.kafkaService.Register(destination.Kafka)
-			if err != nil {
-				WriteError(w, http.StatusForbidden, fmt.Sprintf("failed to connect to kafka: %v", err))
-				return
-			}
-			generatorDestination = producer
-		case event.DestinationTypePostgres:
-			db, err := s.postgresService.Register(destination.Postgres, time.Second)
-			if err != nil {
-				WriteError(w, http.StatusForbidden, fmt.Sprintf("failed to connect to kafka: %v", err))
-				return
+		generatorDestination, err := s.registerDestination(destination)
+		if err != nil {
+			if errors.Is(err, errUnknownDestinationType) {
+				WriteError(w, http.StatusBadRequest, err.Error())
+			} else {
+				WriteError(w, http.StatusForbidden, err.Error())
 			}
-			generatorDestination = db
-		default:
-			WriteError(w, http.StatusBadRequest, fmt.Sprintf("unknown destination type: %v", destination.Type))
 			return
 		}
+		destinationDescs[destination.Id] = destination
 		destinations[destination.Id] = generatorDestination
 	}
 
@@ -95,86 +174,261 @@ func (s *service) handleGeneratorAdd(w http.ResponseWriter, r *http.Request) {
 			WriteError(w, http.StatusBadRequest, fmt.Sprintf("event with id = %s is not specified", schedule.EventId))
 			return
 		}
-		generator, err := s.generatorService.RegisterGenerator(eventDesc, destination)
+		generator, err := s.generatorService.RegisterGenerator(eventDesc, destinationDescs[schedule.DestinationId], destination)
 		if err != nil {
 			WriteError(w, http.StatusForbidden, fmt.Sprintf("failed to create generator: %v", err))
 			return
 		}
-		count, isInfinite := generator.GetStatus()
-		isActive := isInfinite || count > 0
-		response.Generators = append(response.Generators, GeneratorStatus{
-			Id:     generator.GetId(),
-			Active: isActive,
-			Count:  count,
-		})
+		response.Generators = append(response.Generators, toGeneratorStatus(generator))
 	}
 
 	WriteObject(w, response)
 }
 
 func (s *service) handleGeneratorRemove(w http.ResponseWriter, r *http.Request) {
+	generatorId, ok := parseGeneratorId(w, r)
+	if !ok {
+		return
+	}
+
+	err := s.generatorService.UnregisterGenerator(generatorId)
+	if err != nil && errors.Is(err, generator.ErrorNotFound) {
+		WriteError(w, http.StatusNotFound, fmt.Sprintf("generator with id is not found: %v", generatorId))
+		return
+	}
+	WriteObject(w, nil)
+}
+
+func (s *service) handleGeneratorStatus(w http.ResponseWriter, r *http.Request) {
+	generatorId, ok := parseGeneratorId(w, r)
+	if !ok {
+		return
+	}
+
+	generator := s.generatorService.GetGenerator(generatorId)
+	if generator == nil {
+		WriteError(w, http.StatusNotFound, fmt.Sprintf("generator with id is not found: %v", generatorId))
+		return
+	}
+	WriteObject(w, toGeneratorStatus(generator))
+}
+
+func (s *service) handleGeneratorPause(w http.ResponseWriter, r *http.Request) {
+	generatorId, ok := parseGeneratorId(w, r)
+	if !ok {
+		return
+	}
+	s.writeGeneratorResult(w, generatorId, s.generatorService.PauseGenerator)
+}
+
+func (s *service) handleGeneratorResume(w http.ResponseWriter, r *http.Request) {
+	generatorId, ok := parseGeneratorId(w, r)
+	if !ok {
+		return
+	}
+	s.writeGeneratorResult(w, generatorId, s.generatorService.ResumeGenerator)
+}
+
+func (s *service) handleGeneratorUpdate(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	generatorId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse request id %v: %v", id, err))
+		return
+	}
+
 	request := struct {
-		Id string `json:"id"`
+		Interval *string         `json:"interval"`
+		Rate     *event.RateDesc `json:"rate"`
+		Count    *int64          `json:"count"`
+		Dataset  *string         `json:"dataset"`
 	}{}
+	err = ParseRequest(r, &request)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse request: %v", err))
+		return
+	}
+
+	update := generator.Update{
+		Rate:    request.Rate,
+		Count:   request.Count,
+		Dataset: request.Dataset,
+	}
+	if request.Interval != nil {
+		interval, err := time.ParseDuration(*request.Interval)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse time interval %v: %v", *request.Interval, err))
+			return
+		}
+		update.Interval = &interval
+	}
+
+	s.writeGeneratorResult(w, generatorId, func(generatorId uint64) (*generator.Generator, error) {
+		return s.generatorService.UpdateGenerator(generatorId, update)
+	})
+}
+
+func (s *service) writeGeneratorResult(w http.ResponseWriter, generatorId uint64, action func(uint64) (*generator.Generator, error)) {
+	g, err := action(generatorId)
+	if err != nil {
+		switch {
+		case errors.Is(err, generator.ErrorNotFound):
+			WriteError(w, http.StatusNotFound, fmt.Sprintf("generator with id is not found: %v", generatorId))
+		case errors.Is(err, generator.ErrorStopped):
+			WriteError(w, http.StatusConflict, fmt.Sprintf("generator with id is stopped: %v", generatorId))
+		default:
+			WriteError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	WriteObject(w, toGeneratorStatus(g))
+}
+
+func (s *service) handleGeneratorList(w http.ResponseWriter, r *http.Request) {
+	request := struct {
+		Type    string `json:"type"`
+		Dataset string `json:"dataset"`
+		State   string `json:"state"`
+		Cursor  string `json:"cursor"`
+		Limit   int    `json:"limit"`
+	}{
+		Limit: defaultListLimit,
+	}
 	err := ParseRequest(r, &request)
 	if err != nil {
 		WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse request: %v", err))
 		return
 	}
 
-	if request.Id == "" {
-		WriteError(w, http.StatusBadRequest, "generator id is empty or not supplied")
+	if request.Limit <= 0 || request.Limit > maxListLimit {
+		WriteError(w, http.StatusBadRequest, fmt.Sprintf("limit must be in range [1, %d]", maxListLimit))
 		return
 	}
 
-	generatorId, err := strconv.ParseUint(request.Id, 10, 64)
+	filter := generator.Filter{
+		DestinationType: request.Type,
+		Dataset:         request.Dataset,
+	}
+	if request.State != "" {
+		state, err := generator.ParseState(request.State)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		filter.State = &state
+	}
+
+	var cursor uint64
+	if request.Cursor != "" {
+		cursor, err = strconv.ParseUint(request.Cursor, 10, 64)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse cursor %v: %v", request.Cursor, err))
+			return
+		}
+	}
+
+	generators, next := s.generatorService.ListGenerators(filter, cursor, request.Limit)
+	response := ListGeneratorsResponse{
+		Generators: make([]GeneratorStatus, 0, len(generators)),
+	}
+	for _, generator := range generators {
+		response.Generators = append(response.Generators, toGeneratorStatus(generator))
+	}
+	if next != 0 {
+		response.Cursor = strconv.FormatUint(next, 10)
+	}
+	WriteObject(w, response)
+}
+
+func (s *service) handleGeneratorPending(w http.ResponseWriter, r *http.Request) {
+	registrations := s.generatorService.GetPending()
+	response := PendingGeneratorsResponse{
+		Generators: make([]PendingGenerator, 0, len(registrations)),
+	}
+	for _, registration := range registrations {
+		response.Generators = append(response.Generators, PendingGenerator{
+			Id:              strconv.FormatUint(registration.Id, 10),
+			DestinationType: registration.Destination.Type,
+			EventId:         registration.Event.Id,
+			Count:           registration.Count,
+			Paused:          registration.Paused,
+		})
+	}
+	WriteObject(w, response)
+}
+
+func (s *service) handleEventPreview(w http.ResponseWriter, r *http.Request) {
+	var request PreviewRequest
+	err := ParseRequest(r, &request)
 	if err != nil {
-		WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse request id %v: %v", request.Id, err))
+		WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse request: %v", err))
+		return
+	}
+	if len(request.Schema) == 0 {
+		WriteError(w, http.StatusBadRequest, "schema is not specified")
+		return
+	}
+	if request.N == 0 {
+		request.N = defaultPreviewCount
+	}
+	if request.N < 0 || request.N > maxPreviewCount {
+		WriteError(w, http.StatusBadRequest, fmt.Sprintf("n must be between 1 and %d", maxPreviewCount))
 		return
 	}
 
-	err = s.generatorService.UnregisterGenerator(generatorId)
-	if err != nil && errors.Is(err, generator.ErrorNotFound) {
-		WriteError(w, http.StatusNotFound, fmt.Sprintf("generator with id is not found: %v", generatorId))
+	response := PreviewResponse{Events: make([]json.RawMessage, 0, request.N)}
+	composer, err := event.NewComposerByContent(request.Dataset, s.generatorService.GetInstanceId(), previewName, request.Schema)
+	if err != nil {
+		response.Error = event.ToSchemaError(err)
+		WriteObject(w, response)
 		return
 	}
-	WriteObject(w, nil)
+	if request.Seed != nil {
+		composer.SetSeed(*request.Seed)
+	}
+
+	var sample *event.Event
+	for i := 0; i < request.N; i++ {
+		evt, err := composer.Compose()
+		if err != nil {
+			response.Error = event.ToSchemaError(err)
+			break
+		}
+		if sample == nil {
+			sample = evt
+		}
+		response.Events = append(response.Events, json.RawMessage(evt.Json))
+	}
+
+	// Table is created along the first event, the same way postgres destination does
+	if sample != nil {
+		response.Columns, err = postgres.InferColumns(sample.Object)
+		if err != nil && response.Error == nil {
+			response.Error = &event.SchemaError{Message: fmt.Sprintf("failed to infer postgres columns: %v", err)}
+		}
+	}
+	WriteObject(w, response)
 }
 
-func (s *service) handleGeneratorStatus(w http.ResponseWriter, r *http.Request) {
+func parseGeneratorId(w http.ResponseWriter, r *http.Request) (uint64, bool) {
 	request := struct {
 		Id string `json:"id"`
 	}{}
 	err := ParseRequest(r, &request)
 	if err != nil {
 		WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse request: %v", err))
-		return
+		return 0, false
 	}
 
 	if request.Id == "" {
 		WriteError(w, http.StatusBadRequest, "generator id is empty or not supplied")
-		return
+		return 0, false
 	}
 
 	generatorId, err := strconv.ParseUint(request.Id, 10, 64)
 	if err != nil {
 		WriteError(w, http.StatusBadRequest, fmt.Sprintf("failed to parse request id %v: %v", request.Id, err))
-		return
-	}
-
-	generator := s.generatorService.GetGenerator(generatorId)
-	if generator == nil {
-		WriteError(w, http.StatusNotFound, fmt.Sprintf("generator with id is not found: %v", generatorId))
-		return
+		return 0, false
 	}
-	count, isInfinite := generator.GetStatus()
-	isActive := isInfinite || count > 0
-	WriteObject(
-		w,
-		GeneratorStatus{
-			Id:     generator.GetId(),
-			Active: isActive,
-			Count:  count,
-		},
-	)
+	return generatorId, true
 }
@@ -178,7 +178,7 @@ func ParseRequest(r *http.Request, out interface{}) error {
 	var err error
 	var requestDataParser RequestDataParser
 	switch r.Method {
-	case http.MethodPost, http.MethodPut:
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
 		var mimeType string
 		mimeType, _, err = mime.ParseMediaType(r.Header.Get("content-type"))
 		if err != nil {
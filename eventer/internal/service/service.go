@@ -1,15 +1,25 @@
 package service
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 
 	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+	"github.com/sibedge-llc/dp-services/eventer/internal/event"
 	"github.com/sibedge-llc/dp-services/eventer/internal/generator"
 	"github.com/sibedge-llc/dp-services/eventer/internal/kafka"
 	"github.com/sibedge-llc/dp-services/eventer/internal/postgres"
+	"github.com/sibedge-llc/dp-services/eventer/internal/webhook"
+)
+
+var (
+	errUnknownDestinationType = errors.New("unknown destination type")
 )
 
 type service struct {
@@ -17,14 +27,16 @@ type service struct {
 	generatorService *generator.Service
 	kafkaService     *kafka.Service
 	postgresService  *postgres.Service
+	webhookService   *webhook.Service
 }
 
-func New(cfg *config.ServiceConfig, kafkaService *kafka.Service, postgresService *postgres.Service, generatorService *generator.Service) *service {
+func New(cfg *config.ServiceConfig, kafkaService *kafka.Service, postgresService *postgres.Service, webhookService *webhook.Service, generatorService *generator.Service) *service {
 	return &service{
 		Listen:           cfg.Listen,
 		generatorService: generatorService,
 		kafkaService:     kafkaService,
 		postgresService:  postgresService,
+		webhookService:   webhookService,
 	}
 }
 
@@ -34,6 +46,61 @@ func (s *service) ListenAndServe() error {
 	mux.HandleFunc("/generator/add", s.handleGeneratorAdd).Methods(http.MethodPost)
 	mux.HandleFunc("/generator/remove", s.handleGeneratorRemove).Methods(http.MethodPost)
 	mux.HandleFunc("/generator/status", s.handleGeneratorStatus).Methods(http.MethodGet)
+	mux.HandleFunc("/generator/pause", s.handleGeneratorPause).Methods(http.MethodPost)
+	mux.HandleFunc("/generator/resume", s.handleGeneratorResume).Methods(http.MethodPost)
+	mux.HandleFunc("/generator/{id:[0-9]+}", s.handleGeneratorUpdate).Methods(http.MethodPatch)
+	mux.HandleFunc("/generators", s.handleGeneratorList).Methods(http.MethodGet)
+	mux.HandleFunc("/generators/pending", s.handleGeneratorPending).Methods(http.MethodGet)
+	mux.HandleFunc("/event/preview", s.handleEventPreview).Methods(http.MethodPost)
+	mux.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet)
 	zap.L().Info("Server started", zap.String("listen", s.Listen))
 	return http.ListenAndServe(s.Listen, mux)
 }
+
+func (s *service) RestoreGenerators() error {
+	registrations, err := s.generatorService.LoadRegistrations()
+	if err != nil {
+		return err
+	}
+	for _, registration := range registrations {
+		destination, err := s.registerDestination(registration.Destination)
+		if err != nil {
+			zap.L().Error("failed to restore generator destination", zap.Uint64("id", registration.Id), zap.Error(err))
+			continue
+		}
+		generator, err := s.generatorService.RestoreGenerator(registration, destination)
+		if err != nil {
+			zap.L().Error("failed to restore generator", zap.Uint64("id", registration.Id), zap.Error(err))
+			continue
+		}
+		count, _ := generator.GetStatus()
+		zap.L().Info("Generator restored", zap.String("id", generator.GetId()), zap.Int64("count", count))
+	}
+	s.generatorService.DropRestored()
+	return nil
+}
+
+func (s *service) registerDestination(desc event.DestinationDesc) (generator.Destinaton, error) {
+	switch desc.Type {
+	case event.DestinationTypeKafka:
+		producer, err := s.kafkaService.Register(desc.Kafka)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to kafka: %w", err)
+		}
+		return producer, nil
+	case event.DestinationTypePostgres:
+		db, err := s.postgresService.Register(desc.Postgres, time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+		}
+		return db, nil
+	case event.DestinationTypeHttp:
+		endpoint, err := s.webhookService.Register(desc.Http)
+		if err != nil {
+			return nil, fmt.Errorf("failed to make http endpoint: %w", err)
+		}
+		return endpoint, nil
+	default:
+		return nil, fmt.Errorf("%w: %v", errUnknownDestinationType, desc.Type)
+	}
+}
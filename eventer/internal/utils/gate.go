@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"context"
+	"sync"
+)
+
+type Gate struct {
+	lock   sync.Mutex
+	closed bool
+	opened chan struct{}
+}
+
+func NewGate() *Gate {
+	opened := make(chan struct{})
+	close(opened)
+	return &Gate{opened: opened}
+}
+
+func (g *Gate) Close() bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.closed {
+		return false
+	}
+	g.closed = true
+	g.opened = make(chan struct{})
+	return true
+}
+
+func (g *Gate) Open() bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if !g.closed {
+		return false
+	}
+	g.closed = false
+	close(g.opened)
+	return true
+}
+
+func (g *Gate) IsClosed() bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.closed
+}
+
+// Wait blocks while the gate is closed. It returns false if the context is done.
+func (g *Gate) Wait(ctx context.Context) bool {
+	g.lock.Lock()
+	opened := g.opened
+	g.lock.Unlock()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-opened:
+		return true
+	}
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGate(t *testing.T) {
+	g := NewGate()
+	if g.IsClosed() {
+		t.Fatal("new gate is closed")
+	}
+	if !g.Wait(context.Background()) {
+		t.Fatal("wait on opened gate failed")
+	}
+	if !g.Close() || g.Close() {
+		t.Fatal("unexpected close result")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if g.Wait(ctx) {
+		t.Fatal("wait on closed gate passed")
+	}
+
+	done := make(chan bool)
+	go func() {
+		done <- g.Wait(context.Background())
+	}()
+	if !g.Open() || g.Open() {
+		t.Fatal("unexpected open result")
+	}
+	if !<-done {
+		t.Fatal("wait is not released by open")
+	}
+}
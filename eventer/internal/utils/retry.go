@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RetryPolicy calls the operation again with exponential backoff while it fails with the retryable error
+type RetryPolicy struct {
+	attempts    int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	isRetryable func(error) bool
+}
+
+// NewRetryPolicy makes the policy of the attempts including the first one, the operation is called once
+// if attempts is not positive. Backoff is not limited if the max one is empty.
+func NewRetryPolicy(attempts int, backoff string, maxBackoff string, isRetryable func(error) bool) (RetryPolicy, error) {
+	p := RetryPolicy{attempts: attempts, isRetryable: isRetryable}
+	if p.attempts <= 0 {
+		p.attempts = 1
+	}
+	var err error
+	if backoff != "" {
+		p.backoff, err = time.ParseDuration(backoff)
+		if err != nil {
+			return p, fmt.Errorf("failed to parse retry backoff %v: %w", backoff, err)
+		}
+	}
+	if maxBackoff != "" {
+		p.maxBackoff, err = time.ParseDuration(maxBackoff)
+		if err != nil {
+			return p, fmt.Errorf("failed to parse retry max backoff %v: %w", maxBackoff, err)
+		}
+	}
+	return p, nil
+}
+
+// Do calls f until it succeeds, fails with the error which can't be fixed by retry or attempts are over,
+// backoff is doubled after every attempt. Retries are logged by the name of the operation along with the fields.
+func (p RetryPolicy) Do(ctx context.Context, name string, f func() error, fields ...zap.Field) error {
+	backoff := p.backoff
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= p.attempts || !p.isRetryable(err) {
+			return err
+		}
+		zap.L().Warn(name+" failed, retrying", append(fields, zap.Int("attempt", attempt), zap.Error(err))...)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if p.maxBackoff > 0 && backoff > p.maxBackoff {
+			backoff = p.maxBackoff
+		}
+	}
+}
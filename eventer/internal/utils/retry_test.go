@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+var errFatal = errors.New("fatal")
+
+func TestRetryPolicy(t *testing.T) {
+	p, err := NewRetryPolicy(3, "1ms", "2ms", func(err error) bool { return err != errFatal })
+	if err != nil {
+		t.Fatal("create retry policy failed", err)
+	}
+
+	calls := 0
+	err = p.Do(context.Background(), "test", func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("expected success after 3 calls, got %d calls and error %v", calls, err)
+	}
+
+	calls = 0
+	err = p.Do(context.Background(), "test", func() error {
+		calls++
+		return errFatal
+	})
+	if err != errFatal || calls != 1 {
+		t.Errorf("expected fatal error not to be retried, got %d calls", calls)
+	}
+
+	calls = 0
+	err = p.Do(context.Background(), "test", func() error {
+		calls++
+		return errors.New("unavailable")
+	})
+	if err == nil || calls != 3 {
+		t.Errorf("expected 3 attempts, got %d calls", calls)
+	}
+
+	if _, err := NewRetryPolicy(3, "1 ms", "", nil); err == nil {
+		t.Error("expected error of invalid backoff")
+	}
+}
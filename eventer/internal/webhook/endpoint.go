@@ -0,0 +1,342 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+	"github.com/sibedge-llc/dp-services/eventer/internal/event"
+	"github.com/sibedge-llc/dp-services/eventer/internal/metrics"
+	"github.com/sibedge-llc/dp-services/eventer/internal/utils"
+)
+
+const maxErrorBodySize = 512
+
+// Endpoint sends events to the url by concurrent requests, results of the requests are passed
+// to the delivery reports of the events
+type Endpoint struct {
+	ctx           context.Context
+	id            uint64
+	cfg           *config.HttpConfig
+	client        *http.Client
+	retry         utils.RetryPolicy
+	batchSize     int
+	flushInterval time.Duration
+	batchLock     sync.Mutex
+	batch         []*event.Event
+	requests      chan request
+	pendingLock   sync.Mutex
+	seq           uint64
+	pending       map[uint64]bool
+	done          *sync.Cond
+}
+
+type request struct {
+	seq   uint64
+	batch []*event.Event
+}
+
+func NewEndpoint(ctx context.Context, id uint64, cfg *config.HttpConfig) (*Endpoint, error) {
+	if cfg.Url == "" {
+		return nil, errors.New("url is empty or not provided")
+	}
+	u, err := url.Parse(cfg.Url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported url scheme %s", u.Scheme)
+	}
+
+	switch cfg.BatchFormat {
+	case "", config.HttpBatchFormatArray, config.HttpBatchFormatNdjson:
+	default:
+		return nil, fmt.Errorf("unknown batch format %s", cfg.BatchFormat)
+	}
+
+	var timeout time.Duration
+	if cfg.Timeout != "" {
+		timeout, err = time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse timeout %v: %w", cfg.Timeout, err)
+		}
+	}
+	var flushInterval time.Duration
+	if cfg.FlushInterval != "" {
+		flushInterval, err = time.ParseDuration(cfg.FlushInterval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse flush interval %v: %w", cfg.FlushInterval, err)
+		}
+	}
+	retry, err := utils.NewRetryPolicy(cfg.RetryAttempts, cfg.RetryBackoff, cfg.RetryMaxBackoff, isRetryable)
+	if err != nil {
+		return nil, err
+	}
+
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	e := &Endpoint{
+		ctx:           ctx,
+		id:            id,
+		cfg:           cfg,
+		client:        &http.Client{Timeout: timeout},
+		retry:         retry,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		batch:         make([]*event.Event, 0, batchSize),
+		requests:      make(chan request),
+		pending:       make(map[uint64]bool),
+	}
+	e.done = sync.NewCond(&e.pendingLock)
+	for i := 0; i < concurrency; i++ {
+		go e.work(ctx)
+	}
+	if batchSize > 1 && flushInterval > 0 {
+		go e.run(ctx)
+	}
+	return e, nil
+}
+
+func (e *Endpoint) GetConfig() *config.HttpConfig {
+	return e.cfg
+}
+
+func (e *Endpoint) Init(evt *event.Event) error {
+	return nil
+}
+
+func (e *Endpoint) GetId() uint64 {
+	return e.id
+}
+
+func (e *Endpoint) GetType() string {
+	return event.DestinationTypeHttp
+}
+
+func (e *Endpoint) GetTarget() string {
+	return e.cfg.Url
+}
+
+// Send queues the event, it's sent once the batch is full
+func (e *Endpoint) Send(evt *event.Event) error {
+	e.batchLock.Lock()
+	e.batch = append(e.batch, evt)
+	var batch []*event.Event
+	if len(e.batch) >= e.batchSize {
+		batch = e.takeBatch()
+	}
+	e.batchLock.Unlock()
+
+	if batch == nil {
+		return nil
+	}
+	return e.enqueue(batch)
+}
+
+func (e *Endpoint) SendBatch(evts []*event.Event) error {
+	for _, evt := range evts {
+		err := e.Send(evt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush sends the queued events and waits until the requests queued before are done, requests queued later
+// by the other generators sharing the endpoint are not waited
+func (e *Endpoint) Flush() {
+	e.batchLock.Lock()
+	batch := e.takeBatch()
+	e.batchLock.Unlock()
+	if batch != nil {
+		if err := e.enqueue(batch); err != nil {
+			zap.L().Error("failed to flush events", zap.String("url", e.cfg.Url), zap.Error(err))
+		}
+	}
+	e.pendingLock.Lock()
+	last := e.seq
+	for e.hasPending(last) {
+		e.done.Wait()
+	}
+	e.pendingLock.Unlock()
+}
+
+// hasPending tells whether any request up to the sequence number is queued or being sent
+func (e *Endpoint) hasPending(last uint64) bool {
+	for seq := range e.pending {
+		if seq <= last {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Endpoint) Close() {
+	e.Flush()
+	e.client.CloseIdleConnections()
+}
+
+func (e *Endpoint) takeBatch() []*event.Event {
+	if len(e.batch) == 0 {
+		return nil
+	}
+	batch := e.batch
+	e.batch = make([]*event.Event, 0, e.batchSize)
+	return batch
+}
+
+// enqueue waits for the free worker, so the sender is slowed down to the pace of requests
+func (e *Endpoint) enqueue(batch []*event.Event) error {
+	e.pendingLock.Lock()
+	e.seq++
+	seq := e.seq
+	e.pending[seq] = true
+	e.pendingLock.Unlock()
+
+	select {
+	case e.requests <- request{seq: seq, batch: batch}:
+		return nil
+	case <-e.ctx.Done():
+		e.complete(seq)
+		report(batch, e.ctx.Err())
+		return e.ctx.Err()
+	}
+}
+
+func (e *Endpoint) complete(seq uint64) {
+	e.pendingLock.Lock()
+	delete(e.pending, seq)
+	e.done.Broadcast()
+	e.pendingLock.Unlock()
+}
+
+func (e *Endpoint) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case r := <-e.requests:
+			err := e.post(r.batch)
+			if err != nil {
+				zap.L().Error("failed to send events", zap.String("url", e.cfg.Url), zap.Int("events", len(r.batch)), zap.Error(err))
+			}
+			report(r.batch, err)
+			e.complete(r.seq)
+		}
+	}
+}
+
+func (e *Endpoint) run(ctx context.Context) {
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.batchLock.Lock()
+			batch := e.takeBatch()
+			e.batchLock.Unlock()
+			if batch != nil {
+				_ = e.enqueue(batch)
+			}
+		}
+	}
+}
+
+func (e *Endpoint) post(batch []*event.Event) error {
+	body, contentType := e.encode(batch)
+	return e.retry.Do(e.ctx, "http request", func() error {
+		return e.request(body, contentType)
+	}, zap.String("url", e.cfg.Url))
+}
+
+// encode makes the body of the single event or the batch of events as json array or NDJSON
+func (e *Endpoint) encode(batch []*event.Event) ([]byte, string) {
+	if e.batchSize == 1 {
+		return batch[0].Json, "application/json"
+	}
+	var buf bytes.Buffer
+	if e.cfg.BatchFormat == config.HttpBatchFormatNdjson {
+		for _, evt := range batch {
+			buf.Write(evt.Json)
+			buf.WriteByte('\n')
+		}
+		return buf.Bytes(), "application/x-ndjson"
+	}
+	buf.WriteByte('[')
+	for i, evt := range batch {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(evt.Json)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), "application/json"
+}
+
+func (e *Endpoint) request(body []byte, contentType string) error {
+	method := e.cfg.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequestWithContext(e.ctx, method, e.cfg.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range e.cfg.Headers {
+		req.Header.Set(key, value)
+	}
+	if e.cfg.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+e.cfg.AuthToken)
+	}
+
+	start := time.Now()
+	resp, err := e.client.Do(req)
+	if err != nil {
+		metrics.HttpRequestDuration.WithLabelValues(fmt.Sprint(e.id), "error").Observe(time.Since(start).Seconds())
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	// Rest of the body is drained to reuse the connection
+	_, _ = io.Copy(io.Discard, resp.Body)
+	metrics.HttpRequestDuration.WithLabelValues(fmt.Sprint(e.id), strconv.Itoa(resp.StatusCode)).Observe(time.Since(start).Seconds())
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &statusError{code: resp.StatusCode, body: string(bytes.TrimSpace(data))}
+	}
+	return nil
+}
+
+func report(batch []*event.Event, err error) {
+	for _, evt := range batch {
+		if evt.Delivery == nil {
+			continue
+		}
+		if err != nil {
+			evt.Delivery.Failed(err)
+		} else {
+			evt.Delivery.Delivered()
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+	"github.com/sibedge-llc/dp-services/eventer/internal/event"
+)
+
+type testReport struct {
+	lock      sync.Mutex
+	delivered int
+	failed    []error
+}
+
+func (r *testReport) GetGeneratorId() string {
+	return "test"
+}
+
+func (r *testReport) Delivered() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.delivered++
+}
+
+func (r *testReport) Failed(err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.failed = append(r.failed, err)
+}
+
+type testRequest struct {
+	method      string
+	contentType string
+	auth        string
+	tenant      string
+	body        string
+}
+
+func testServer(t *testing.T, statuses ...int) (*httptest.Server, func() []testRequest) {
+	var lock sync.Mutex
+	var requests []testRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read request: %v", err)
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		requests = append(requests, testRequest{
+			method:      r.Method,
+			contentType: r.Header.Get("Content-Type"),
+			auth:        r.Header.Get("Authorization"),
+			tenant:      r.Header.Get("X-Tenant"),
+			body:        string(body),
+		})
+		if len(requests) <= len(statuses) {
+			w.WriteHeader(statuses[len(requests)-1])
+		}
+	}))
+	return server, func() []testRequest {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]testRequest(nil), requests...)
+	}
+}
+
+func testEvents(report event.DeliveryReporter, jsons ...string) []*event.Event {
+	evts := make([]*event.Event, 0, len(jsons))
+	for _, json := range jsons {
+		evts = append(evts, &event.Event{Json: event.EventJson(json), Delivery: report})
+	}
+	return evts
+}
+
+func TestEndpointBatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server, requests := testServer(t)
+	defer server.Close()
+
+	for _, test := range []struct {
+		format      string
+		contentType string
+		bodies      []string
+	}{
+		{config.HttpBatchFormatArray, "application/json", []string{`[{"id":1},{"id":2}]`, `[{"id":3}]`}},
+		{config.HttpBatchFormatNdjson, "application/x-ndjson", []string{"{\"id\":1}\n{\"id\":2}\n", "{\"id\":3}\n"}},
+	} {
+		before := len(requests())
+		e, err := NewEndpoint(ctx, 1, &config.HttpConfig{
+			Url:         server.URL,
+			Method:      http.MethodPut,
+			Headers:     map[string]string{"X-Tenant": "t1"},
+			AuthToken:   "secret",
+			BatchSize:   2,
+			BatchFormat: test.format,
+			Concurrency: 2,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		report := &testReport{}
+		err = e.SendBatch(testEvents(report, `{"id":1}`, `{"id":2}`, `{"id":3}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.Flush()
+
+		sent := requests()[before:]
+		if len(sent) != 2 || report.delivered != 3 || len(report.failed) != 0 {
+			t.Fatalf("unexpected requests %v, delivered %d, failed %v", sent, report.delivered, report.failed)
+		}
+		// Batches are sent concurrently, so their order is not defined
+		bodies := make([]string, 0, len(sent))
+		for _, request := range sent {
+			if request.method != http.MethodPut || request.contentType != test.contentType || request.auth != "Bearer secret" || request.tenant != "t1" {
+				t.Errorf("unexpected request %+v", request)
+			}
+			bodies = append(bodies, request.body)
+		}
+		sort.Strings(bodies)
+		if !reflect.DeepEqual(bodies, test.bodies) {
+			t.Errorf("expected bodies %q, got %q", test.bodies, bodies)
+		}
+	}
+}
+
+func TestEndpointRetry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server, requests := testServer(t, http.StatusServiceUnavailable, http.StatusOK, http.StatusBadRequest)
+	defer server.Close()
+
+	e, err := NewEndpoint(ctx, 1, &config.HttpConfig{
+		Url:           server.URL,
+		RetryAttempts: 3,
+		RetryBackoff:  "1ms",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := &testReport{}
+	for _, evt := range testEvents(report, `{"id":1}`, `{"id":2}`) {
+		if err := e.Send(evt); err != nil {
+			t.Fatal(err)
+		}
+		e.Flush()
+	}
+
+	// 503 is retried, 400 is not
+	sent := requests()
+	if len(sent) != 3 || sent[0].body != `{"id":1}` || sent[1].body != `{"id":1}` || sent[2].body != `{"id":2}` {
+		t.Fatalf("unexpected requests %v", sent)
+	}
+	var statusErr *statusError
+	if report.delivered != 1 || len(report.failed) != 1 || !errors.As(report.failed[0], &statusErr) || statusErr.code != http.StatusBadRequest {
+		t.Errorf("unexpected delivered %d, failed %v", report.delivered, report.failed)
+	}
+}
+
+func TestEndpointFlush(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gates := map[string]chan struct{}{
+		`{"id":1}`: make(chan struct{}),
+		`{"id":2}`: make(chan struct{}),
+	}
+	received := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- string(body)
+		<-gates[string(body)]
+	}))
+	defer server.Close()
+	defer close(gates[`{"id":2}`])
+
+	e, err := NewEndpoint(ctx, 1, &config.HttpConfig{Url: server.URL, Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := &testReport{}
+	evts := testEvents(report, `{"id":1}`, `{"id":2}`)
+	if err := e.Send(evts[0]); err != nil {
+		t.Fatal(err)
+	}
+	<-received
+
+	flushed := make(chan struct{})
+	go func() {
+		e.Flush()
+		close(flushed)
+	}()
+	// Request sent after Flush by the other generator sharing the endpoint is still in progress
+	time.Sleep(10 * time.Millisecond)
+	if err := e.Send(evts[1]); err != nil {
+		t.Fatal(err)
+	}
+	<-received
+	close(gates[`{"id":1}`])
+
+	select {
+	case <-flushed:
+	case <-time.After(time.Second):
+		t.Fatal("flush waits for the request sent after it")
+	}
+}
@@ -0,0 +1,32 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type statusError struct {
+	code int
+	body string
+}
+
+func (e *statusError) Error() string {
+	if e.body == "" {
+		return fmt.Sprintf("request failed with status %d", e.code)
+	}
+	return fmt.Sprintf("request failed with status %d: %s", e.code, e.body)
+}
+
+// isRetryable tells whether the error is caused by the server state or connection rather than the request
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code >= http.StatusInternalServerError
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+	"github.com/sibedge-llc/dp-services/eventer/internal/utils"
+)
+
+type Service struct {
+	ctx        context.Context
+	lock       sync.Mutex
+	endpoints  map[uint64]*Endpoint
+	defaultCfg *config.HttpConfig
+}
+
+// New keeps the default config only, the default url is optional unlike the other destinations
+func New(ctx context.Context, cfg *config.HttpConfig) *Service {
+	return &Service{
+		ctx:        ctx,
+		endpoints:  make(map[uint64]*Endpoint, 1),
+		defaultCfg: cfg,
+	}
+}
+
+func (s *Service) Register(cfg *config.HttpConfig) (*Endpoint, error) {
+	if cfg == nil {
+		if s.defaultCfg.Url == "" {
+			return nil, errors.New("http destination config is not provided")
+		}
+		cfg = s.defaultCfg
+	}
+
+	id, err := utils.ObjectToJsonId(*cfg, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make id for http config: %w", err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	endpoint, ok := s.endpoints[id]
+	if !ok {
+		// Supplied config may be the default one itself, so the endpoint gets the filled copy
+		c := *cfg
+		cfg = &c
+		defaultCfg := s.defaultCfg
+		// Credentials of the default url must not be sent to the url supplied by the caller
+		if cfg.Url == "" {
+			cfg.Url = defaultCfg.Url
+			if cfg.AuthToken == "" {
+				cfg.AuthToken = defaultCfg.AuthToken
+			}
+			// Headers of the destination are added to the default ones
+			headers := make(map[string]string, len(defaultCfg.Headers)+len(cfg.Headers))
+			for key, value := range defaultCfg.Headers {
+				headers[key] = value
+			}
+			for key, value := range cfg.Headers {
+				headers[key] = value
+			}
+			cfg.Headers = headers
+		}
+		if cfg.Method == "" {
+			cfg.Method = defaultCfg.Method
+		}
+		if cfg.Timeout == "" {
+			cfg.Timeout = defaultCfg.Timeout
+		}
+		if cfg.BatchSize == 0 {
+			cfg.BatchSize = defaultCfg.BatchSize
+		}
+		if cfg.BatchFormat == "" {
+			cfg.BatchFormat = defaultCfg.BatchFormat
+		}
+		if cfg.FlushInterval == "" {
+			cfg.FlushInterval = defaultCfg.FlushInterval
+		}
+		if cfg.Concurrency == 0 {
+			cfg.Concurrency = defaultCfg.Concurrency
+		}
+		if cfg.RetryAttempts == 0 {
+			cfg.RetryAttempts = defaultCfg.RetryAttempts
+		}
+		if cfg.RetryBackoff == "" {
+			cfg.RetryBackoff = defaultCfg.RetryBackoff
+		}
+		if cfg.RetryMaxBackoff == "" {
+			cfg.RetryMaxBackoff = defaultCfg.RetryMaxBackoff
+		}
+		endpoint, err = NewEndpoint(s.ctx, id, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create http endpoint: %w", err)
+		}
+		s.endpoints[id] = endpoint
+	}
+	return endpoint, nil
+}
@@ -0,0 +1,37 @@
+package webhook
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sibedge-llc/dp-services/eventer/internal/config"
+)
+
+func TestServiceRegister(t *testing.T) {
+	s := New(context.Background(), &config.HttpConfig{
+		Url:       "https://ingest.example.com/events",
+		AuthToken: "secret",
+		Headers:   map[string]string{"X-Tenant": "t1"},
+		Timeout:   "5s",
+	})
+
+	e, err := s.Register(&config.HttpConfig{Headers: map[string]string{"X-Source": "eventer"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := e.GetConfig()
+	if cfg.Url != "https://ingest.example.com/events" || cfg.AuthToken != "secret" || cfg.Timeout != "5s" ||
+		!reflect.DeepEqual(cfg.Headers, map[string]string{"X-Tenant": "t1", "X-Source": "eventer"}) {
+		t.Errorf("default settings are not inherited %+v", cfg)
+	}
+
+	e, err = s.Register(&config.HttpConfig{Url: "http://localhost:8080/events"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg = e.GetConfig()
+	if cfg.AuthToken != "" || len(cfg.Headers) != 0 || cfg.Timeout != "5s" {
+		t.Errorf("credentials are inherited by the other url %+v", cfg)
+	}
+}